@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// defaultMultipartMemory 是解析 multipart 表单时保存在内存中的最大字节数，超出部分写入临时文件
+const defaultMultipartMemory = 32 << 20
+
+// BindForm 方法用于将 application/x-www-form-urlencoded 或 multipart/form-data 表单绑定到 dst 指向的结构体。
+// 字段通过 form 标签指定表单字段名，没有标签时使用字段名，标签为 "-" 的字段被忽略。
+// 支持字符串、整数、浮点数、布尔值以及它们的切片，转换失败的字段会在返回的错误中逐一列出
+func (c *Context) BindForm(dst interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := c.Req.ParseMultipartForm(c.multipartMemory()); err != nil {
+			return err
+		}
+	} else if err := c.Req.ParseForm(); err != nil {
+		return err
+	}
+	return bindValues(dst, "form", c.Req.Form)
+}
+
+// BindParams 方法用于将路由参数绑定到 dst 指向的结构体，例如把 /users/:id/posts/:postID 中的参数
+// 绑定到带有 param:"id" 和 param:"postID" 标签的字段上。字段规则和类型转换与 BindForm 相同，
+// 转换失败的字段会在返回的错误中逐一列出
+func (c *Context) BindParams(dst interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for key, value := range c.Params {
+		values[key] = []string{value}
+	}
+	return bindValues(dst, "param", values)
+}
+
+// Validator 接口由需要自行校验的请求结构体实现，BindAndValidate 在解码成功后调用 Validate
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError 结构体表示请求体解码成功但未通过 Validate 校验，用于与 JSON 格式错误区分，
+// 例如前者通常返回 422，后者返回 400
+type ValidationError struct {
+	Err error // Validate 返回的错误
+}
+
+func (e *ValidationError) Error() string {
+	return "validation: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// BindAndValidate 方法用于将请求体中的 JSON 解码到 dst 中，dst 实现了 Validator 时继续调用其 Validate 方法。
+// 解码失败时原样返回解码错误，校验失败时返回 *ValidationError，可以通过 errors.As 区分
+func (c *Context) BindAndValidate(dst interface{}) error {
+	if err := c.BindJSON(dst); err != nil {
+		return err
+	}
+	if v, ok := dst.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	return nil
+}
+
+// multipartMemory 方法用于返回解析 multipart 表单时的内存上限，优先使用路由的 MaxMultipartMemory 配置
+func (c *Context) multipartMemory() int64 {
+	if c.router != nil && c.router.MaxMultipartMemory > 0 {
+		return c.router.MaxMultipartMemory
+	}
+	return defaultMultipartMemory
+}
+
+// FormFile 方法用于获取 multipart 表单中名为 name 的第一个上传文件，调用方负责关闭返回的文件
+func (c *Context) FormFile(name string) (multipart.File, *multipart.FileHeader, error) {
+	if c.Req.MultipartForm == nil {
+		if err := c.Req.ParseMultipartForm(c.multipartMemory()); err != nil {
+			return nil, nil, err
+		}
+	}
+	return c.Req.FormFile(name)
+}
+
+// SaveUploadedFile 方法用于将上传的文件保存到 dst
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// bindValues 函数用于按 tag 标签将 values 中的值绑定到 dst 指向的结构体字段上
+func bindValues(dst interface{}, tag string, values map[string][]string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("bind: dst must be a non-nil pointer to a struct")
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	var failed []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+			for j, s := range vals {
+				if err := setValue(slice.Index(j), s); err != nil {
+					failed = append(failed, fmt.Sprintf("%s: %v", field.Name, err))
+					break
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+		if err := setValue(fv, vals[0]); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", field.Name, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("bind: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// setValue 函数用于将字符串 s 转换为 v 的类型并赋值
+func setValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid float %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type signupForm struct {
+	Name   string   `form:"name"`
+	Age    int      `form:"age"`
+	Agree  bool     `form:"agree"`
+	Tags   []string `form:"tag"`
+	Secret string   `form:"-"`
+}
+
+// postForm 函数用于创建一个 urlencoded 表单的 POST 请求对应的 Context
+func postForm(body string) *Context {
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return newContext(httptest.NewRecorder(), req)
+}
+
+func TestBindForm(t *testing.T) {
+	var form signupForm
+	c := postForm("name=alice&age=30&agree=true&tag=go&tag=web&Secret=x")
+	if err := c.BindForm(&form); err != nil {
+		t.Fatal(err)
+	}
+	want := signupForm{Name: "alice", Age: 30, Agree: true, Tags: []string{"go", "web"}}
+	if !reflect.DeepEqual(form, want) {
+		t.Errorf("BindForm = %+v, want %+v", form, want)
+	}
+}
+
+func TestBindFormConversionErrors(t *testing.T) {
+	var form signupForm
+	err := postForm("name=bob&age=old&agree=maybe").BindForm(&form)
+	want := `bind: Age: invalid integer "old"; Agree: invalid bool "maybe"`
+	if err == nil || err.Error() != want {
+		t.Fatalf("BindForm error = %v, want %q", err, want)
+	}
+	if form.Name != "bob" {
+		t.Errorf("Name = %q, want fields that converted to still be set", form.Name)
+	}
+
+	if err := postForm("name=x").BindForm(form); err == nil {
+		t.Error("BindForm(non-pointer): want error")
+	}
+}
+
+func TestFormFileUpload(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("avatar", "me.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("file contents"))
+	mw.Close()
+
+	dir := t.TempDir()
+	r := newRouter()
+	// 内存上限小于文件大小时，文件会先写入临时文件，读取方式不变
+	r.MaxMultipartMemory = 4
+	r.addRouteCtx(http.MethodPost, "/upload", func(c *Context) {
+		f, fh, err := c.FormFile("avatar")
+		if err != nil {
+			c.String(http.StatusBadRequest, "%v", err)
+			return
+		}
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		if err := c.SaveUploadedFile(fh, filepath.Join(dir, fh.Filename)); err != nil {
+			c.String(http.StatusInternalServerError, "%v", err)
+			return
+		}
+		c.String(http.StatusOK, "%s", data)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "file contents" {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "me.txt"))
+	if err != nil || string(saved) != "file contents" {
+		t.Errorf("saved file = %q, %v", saved, err)
+	}
+}
+
+type updateUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (u *updateUser) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestBindAndValidate(t *testing.T) {
+	bind := func(body string) error {
+		req := httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(body))
+		var u updateUser
+		return newContext(httptest.NewRecorder(), req).BindAndValidate(&u)
+	}
+
+	if err := bind(`{"name":"alice","age":30}`); err != nil {
+		t.Errorf("valid input: %v", err)
+	}
+
+	var syntaxErr *json.SyntaxError
+	err := bind(`{"name":`)
+	var validationErr *ValidationError
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("malformed JSON: error = %v, want a decode error", err)
+	}
+	if err := bind(`{"name":}`); !errors.As(err, &syntaxErr) {
+		t.Errorf("malformed JSON: error = %v, want *json.SyntaxError", err)
+	}
+
+	err = bind(`{"age":30}`)
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("failing Validate: error = %v, want *ValidationError", err)
+	}
+	if err.Error() != "validation: name is required" {
+		t.Errorf("failing Validate: error = %q", err)
+	}
+}
+
+type postParams struct {
+	UserID int `param:"id"`
+	PostID int `param:"postID"`
+}
+
+func TestBindParams(t *testing.T) {
+	r := newRouter()
+	r.GET("/users/:id/posts/:postID", func(w http.ResponseWriter, req *http.Request) {
+		c := newContext(w, req)
+		var p postParams
+		if err := c.BindParams(&p); err != nil {
+			c.String(http.StatusBadRequest, "%v", err)
+			return
+		}
+		c.String(http.StatusOK, "%d/%d", p.UserID, p.PostID)
+	})
+
+	for _, tt := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/7/posts/42", http.StatusOK, "7/42"},
+		{"/users/7/posts/latest", http.StatusBadRequest, `bind: PostID: invalid integer "latest"`},
+		{"/users/me/posts/x", http.StatusBadRequest, `bind: UserID: invalid integer "me"; PostID: invalid integer "x"`},
+	} {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("GET %s: status = %d, body = %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SetTrustedProxies 方法用于设置可信代理的网段，例如 []string{"10.0.0.0/8", "127.0.0.1"}，单个 IP 视为只包含该地址的网段。
+// 只有请求直接来自这些地址时，ClientIP 才会采信 X-Forwarded-For 和 X-Real-IP 头。任意一项无法解析时返回错误，原有设置保持不变
+func (r *router) SetTrustedProxies(cidrs []string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return fmt.Errorf("trusted proxy %q: invalid IP address", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trustedProxies = nets
+	return nil
+}
+
+// hasTrustedProxies 方法用于判断是否设置了可信代理
+func (r *router) hasTrustedProxies() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.trustedProxies) > 0
+}
+
+// isTrustedProxy 方法用于判断 ip 是否位于可信代理网段内
+func (r *router) isTrustedProxy(ip net.IP) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, ipNet := range r.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP 方法用于获取客户端的真实 IP。请求直接来自可信代理时，从右向左检查 X-Forwarded-For，
+// 跳过其中的可信代理，返回第一个不可信的地址；X-Forwarded-For 缺失或格式错误时使用 X-Real-IP。
+// 请求不是来自可信代理、没有设置可信代理或转发头都不可用时，返回 RemoteAddr 中的地址，避免客户端伪造转发头
+func (c *Context) ClientIP() string {
+	remote := c.Req.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	remoteIP := net.ParseIP(remote)
+	if c.router == nil || remoteIP == nil || !c.router.isTrustedProxy(remoteIP) {
+		return remote
+	}
+
+	if ip, ok := c.forwardedFor(); ok {
+		return ip
+	}
+	if ip := net.ParseIP(strings.TrimSpace(c.Req.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return remote
+}
+
+// forwardedFor 方法用于从 X-Forwarded-For 中取出客户端 IP，头缺失或包含无法解析的地址时第二个返回值为 false
+func (c *Context) forwardedFor() (string, bool) {
+	var entries []string
+	for _, value := range c.Req.Header.Values("X-Forwarded-For") {
+		entries = append(entries, strings.Split(value, ",")...)
+	}
+	if len(entries) == 0 {
+		return "", false
+	}
+
+	var ip net.IP
+	for i := len(entries) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(entries[i]))
+		if ip == nil {
+			return "", false
+		}
+		if !c.router.isTrustedProxy(ip) {
+			return ip.String(), true
+		}
+	}
+	// 所有地址都是可信代理时，最左边的地址就是最早的来源
+	return ip.String(), true
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r := newRouter()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	r.addRouteCtx(http.MethodGet, "/ip", func(c *Context) {
+		c.String(http.StatusOK, "%s", c.ClientIP())
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"trusted proxy", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+		{"proxy chain", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"single trusted IP", "192.168.1.1:1234", map[string]string{"X-Real-IP": "203.0.113.8"}, "203.0.113.8"},
+		{"untrusted source", "198.51.100.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7", "X-Real-IP": "203.0.113.8"}, "198.51.100.1"},
+		{"malformed X-Forwarded-For", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "not-an-ip", "X-Real-IP": "203.0.113.9"}, "203.0.113.9"},
+		{"malformed headers", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "garbage", "X-Real-IP": "also garbage"}, "10.0.0.1"},
+		{"no headers", "10.0.0.1:1234", nil, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = tt.remoteAddr
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tt.want {
+			t.Errorf("%s: ClientIP() = %q, want %q", tt.name, w.Body.String(), tt.want)
+		}
+	}
+
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("SetTrustedProxies with an invalid CIDR: want error")
+	}
+}
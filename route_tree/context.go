@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Context 结构体封装了一次请求的响应写入器、请求和路由参数，简化处理函数的编写
+type Context struct {
+	http.ResponseWriter                   // 响应写入器，Context 可以直接当作 http.ResponseWriter 使用
+	Req                 *http.Request     // 当前请求
+	Params              map[string]string // 匹配到的路由参数
+
+	query  url.Values    // 缓存解析后的查询参数，第一次访问时解析
+	router *router       // 处理当前请求的路由，直接构造的 Context 中为 nil
+	state  *requestState // 同一请求的中间件和处理函数共享的状态
+
+	handlers []func(*Context) // UseContext 注册的中间件和最终的处理函数，由 Next 依次执行
+	index    int              // handlers 中下一个要执行的位置
+}
+
+// requestState 结构体保存同一请求中所有 Context 共享的状态，由 handle 在请求开始时创建并放入请求上下文
+type requestState struct {
+	aborted atomic.Bool  // 是否已调用 Abort，Timeout 中间件会在其他 goroutine 中读取
+	pattern atomic.Value // 匹配到的路由规则，主机路由匹配后由内层写入，外层的全局中间件通过 MatchedPattern 读取
+
+	mu   sync.RWMutex           // 保护 keys，Timeout 中间件会在其他 goroutine 中运行处理函数
+	keys map[string]interface{} // 通过 Set 保存的键值，第一次调用 Set 时才创建
+}
+
+// newContext 函数用于根据响应写入器和请求创建 Context，路由参数和请求级共享状态从请求上下文中读取
+func newContext(w http.ResponseWriter, req *http.Request) *Context {
+	params, _ := req.Context().Value(paramsKey).(map[string]string)
+	r, _ := req.Context().Value(routerKey).(*router)
+	state, ok := req.Context().Value(stateKey).(*requestState)
+	if !ok {
+		state = &requestState{}
+	}
+	return &Context{
+		ResponseWriter: w,
+		Req:            req,
+		Params:         params,
+		router:         r,
+		state:          state,
+	}
+}
+
+// Abort 方法用于中止中间件链，调用后尚未执行的中间件和处理函数都不会再执行，
+// 已经在执行的外层中间件不受影响。通常在写出响应后调用，例如鉴权失败返回 401 之后
+func (c *Context) Abort() {
+	c.getState().aborted.Store(true)
+}
+
+// Next 方法用于在 UseContext 注册的中间件中执行链上剩余的部分，返回时后续的中间件和处理函数都已执行完毕。
+// 请求已经被中止或链已经执行完时什么也不做
+func (c *Context) Next() {
+	if c.index >= len(c.handlers) || c.IsAborted() {
+		return
+	}
+	h := c.handlers[c.index]
+	c.index++
+	h(c)
+}
+
+// AbortWithStatus 方法用于写出状态码并中止中间件链，不写响应体
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+// NotFound 方法用于在处理函数中返回与路由一致的 404 响应：优先使用通过 NotFound 设置的处理器，
+// 例如处理函数查找的资源不存在时。调用后中间件链被中止
+func (c *Context) NotFound() {
+	if c.router != nil {
+		c.router.handleNotFound(c, c.Req)
+	} else {
+		http.NotFound(c, c.Req)
+	}
+	c.Abort()
+}
+
+// IsAborted 方法用于判断当前请求是否已经被中止
+func (c *Context) IsAborted() bool {
+	return c.getState().aborted.Load()
+}
+
+// getState 方法用于返回请求级共享状态，直接构造的 Context 在第一次使用时创建一个只属于自己的状态
+func (c *Context) getState() *requestState {
+	if c.state == nil {
+		c.state = &requestState{}
+	}
+	return c.state
+}
+
+// Set 方法用于在当前请求中保存一个键值，供后续的中间件和处理函数通过 Get 读取，例如鉴权中间件保存当前用户
+func (c *Context) Set(key string, value interface{}) {
+	state := c.getState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.keys == nil {
+		state.keys = make(map[string]interface{})
+	}
+	state.keys[key] = value
+}
+
+// Get 方法用于读取当前请求中通过 Set 保存的值，键不存在时第二个返回值为 false
+func (c *Context) Get(key string) (interface{}, bool) {
+	state := c.getState()
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	value, ok := state.keys[key]
+	return value, ok
+}
+
+// RouteMeta 方法用于读取处理当前请求的路由上通过 WithMeta 附加的元数据
+func (c *Context) RouteMeta(key string) (interface{}, bool) {
+	return RouteMeta(c.Req, key)
+}
+
+// Done 方法用于返回请求 context 的 Done 通道，客户端断开连接、服务关闭或 Timeout 中间件超时时关闭。
+// 长时间运行的处理函数应当 select 该通道，及时停止已经没有意义的工作
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+// MatchedRoute 方法用于获取处理当前请求的路由规则，例如 /users/:id
+func (c *Context) MatchedRoute() string {
+	return MatchedPattern(c.Req)
+}
+
+// Param 方法用于获取路由参数的值，参数不存在时返回空字符串
+func (c *Context) Param(key string) string {
+	return c.Params[key]
+}
+
+// ParamDefault 方法用于获取路由参数的值，参数不存在或为空时返回 fallback
+func (c *Context) ParamDefault(key, fallback string) string {
+	if value := c.Params[key]; value != "" {
+		return value
+	}
+	return fallback
+}
+
+// ParamInt 方法用于将路由参数解析为 int
+func (c *Context) ParamInt(key string) (int, error) {
+	value := c.Param(key)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("path param %q: invalid integer %q", key, value)
+	}
+	return n, nil
+}
+
+// ParamInt64 方法用于将路由参数解析为 int64
+func (c *Context) ParamInt64(key string) (int64, error) {
+	value := c.Param(key)
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("path param %q: invalid int64 %q", key, value)
+	}
+	return n, nil
+}
+
+// ParamBool 方法用于将路由参数解析为 bool，接受 strconv.ParseBool 支持的写法
+func (c *Context) ParamBool(key string) (bool, error) {
+	value := c.Param(key)
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("path param %q: invalid bool %q", key, value)
+	}
+	return b, nil
+}
+
+// PathParam 方法用于获取路由参数的值，与 Param 相同，用于和 QueryParam 对照着明确取值来源
+func (c *Context) PathParam(key string) string {
+	return c.Params[key]
+}
+
+// QueryParam 方法用于获取查询参数的第一个值，与 Query 相同，用于和 PathParam 对照着明确取值来源
+func (c *Context) QueryParam(key string) string {
+	return c.queryValues().Get(key)
+}
+
+// Lookup 方法用于获取一个既可以放在路径中、也可以放在查询参数中的值。
+// 路由参数优先：路由中存在名为 key 的参数时返回其值，即使查询参数中也有同名参数；否则返回查询参数的第一个值，都不存在时返回空字符串
+func (c *Context) Lookup(key string) string {
+	if value, ok := c.Params[key]; ok {
+		return value
+	}
+	return c.queryValues().Get(key)
+}
+
+// queryValues 方法用于返回解析后的查询参数，只在第一次调用时解析
+func (c *Context) queryValues() url.Values {
+	if c.query == nil {
+		c.query = c.Req.URL.Query()
+	}
+	return c.query
+}
+
+// Query 方法用于获取查询参数的第一个值，参数不存在时返回空字符串
+func (c *Context) Query(key string) string {
+	return c.queryValues().Get(key)
+}
+
+// QueryDefault 方法用于获取查询参数的第一个值，参数不存在时返回 fallback
+func (c *Context) QueryDefault(key, fallback string) string {
+	if values, ok := c.queryValues()[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return fallback
+}
+
+// QueryInt 方法用于将查询参数解析为整数
+func (c *Context) QueryInt(key string) (int, error) {
+	value := c.Query(key)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("query param %q: invalid integer %q", key, value)
+	}
+	return n, nil
+}
+
+// Status 方法用于写出响应状态码
+func (c *Context) Status(code int) {
+	c.WriteHeader(code)
+}
+
+// String 方法用于以纯文本格式写出响应
+func (c *Context) String(code int, format string, values ...interface{}) {
+	c.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.Status(code)
+	fmt.Fprintf(c, format, values...)
+}
+
+// JSON 方法用于以 JSON 格式写出响应。响应头写出后如果编码失败，只记录日志，不再重复写响应
+func (c *Context) JSON(code int, v interface{}) {
+	c.Header().Set("Content-Type", "application/json")
+	c.Status(code)
+	if err := json.NewEncoder(c).Encode(v); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
+
+// HTML 方法用于渲染通过 LoadHTMLGlob 加载的名为 name 的模板。响应头写出后如果渲染失败，只记录日志，不再重复写响应
+func (c *Context) HTML(code int, name string, data interface{}) {
+	var templates *template.Template
+	if c.router != nil {
+		c.router.mu.RLock()
+		templates = c.router.templates
+		c.router.mu.RUnlock()
+	}
+	if templates == nil {
+		log.Printf("html render error: no templates loaded for %q", name)
+		http.Error(c, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	c.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Status(code)
+	if err := templates.ExecuteTemplate(c, name, data); err != nil {
+		log.Printf("html render error: %v", err)
+	}
+}
+
+// BindJSON 方法用于将请求体中的 JSON 解码到 dst 中
+func (c *Context) BindJSON(dst interface{}) error {
+	return json.NewDecoder(c.Req.Body).Decode(dst)
+}
+
+// Negotiate 方法用于根据请求的 Accept 头（包括 q 值）从 offers 中选出最合适的内容类型，
+// 支持 */* 和 text/* 这样的通配，没有可接受的类型时返回空字符串。请求没有 Accept 头时返回第一个候选类型
+func (c *Context) Negotiate(offers ...string) string {
+	header := c.Req.Header.Get("Accept")
+	if header == "" {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+		return ""
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+	ranges := make([]acceptRange, 0)
+	for _, item := range strings.Split(header, ",") {
+		fields := strings.Split(item, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// 对每个候选类型使用最具体的匹配范围的 q 值：完全匹配 > type/* > */*
+		typ, _, _ := strings.Cut(strings.ToLower(offer), "/")
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			level := -1
+			switch {
+			case r.mediaType == strings.ToLower(offer):
+				level = 2
+			case r.mediaType == typ+"/*":
+				level = 1
+			case r.mediaType == "*/*":
+				level = 0
+			}
+			if level > specificity {
+				q, specificity = r.q, level
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// SSE 方法用于写出一条 Server-Sent Events 消息并立即刷新。data 为字符串时原样写出，
+// 其他类型编码为 JSON；多行数据会拆分为多个 data: 行。底层写入器不支持刷新时返回错误
+func (c *Context) SSE(event string, data interface{}) error {
+	var payload string
+	switch v := data.(type) {
+	case string:
+		payload = v
+	case []byte:
+		payload = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+
+	header := c.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+	}
+
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(payload, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(c.ResponseWriter, b.String()); err != nil {
+		return err
+	}
+	if err := http.NewResponseController(c.ResponseWriter).Flush(); err != nil {
+		return fmt.Errorf("sse: response writer does not support flushing: %w", err)
+	}
+	return nil
+}
+
+// Stream 方法用于分块写出流式响应：反复调用 step 并在每次调用后刷新响应，直到 step 返回 false 或客户端断开连接。
+// 客户端断开（请求的 context 结束）导致停止时返回 true。底层写入器不支持刷新时数据仍会写出，只是不会立即发送
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Done()
+	rc := http.NewResponseController(c.ResponseWriter)
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+		}
+		more := step(c.ResponseWriter)
+		rc.Flush()
+		if !more {
+			return false
+		}
+	}
+}
+
+// Hijack 方法用于接管底层的 TCP 连接，例如升级为 WebSocket。接管后响应由调用方自行负责写出和关闭连接。
+// 底层写入器不支持接管时返回错误
+func (c *Context) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(c.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, fmt.Errorf("hijack: response writer does not support hijacking: %w", err)
+	}
+	return conn, rw, nil
+}
+
+// Redirect 方法用于以 code 状态码重定向到 location，code 不是 3xx 重定向状态码时不写响应并返回错误
+func (c *Context) Redirect(code int, location string) error {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		return fmt.Errorf("redirect: invalid status code %d", code)
+	}
+	http.Redirect(c, c.Req, location, code)
+	return nil
+}
+
+// RedirectPermanent 方法用于永久重定向：GET 和 HEAD 请求使用 301，其他方法使用 308 以保留请求方法和请求体
+func (c *Context) RedirectPermanent(location string) {
+	code := http.StatusPermanentRedirect
+	if c.Req.Method == http.MethodGet || c.Req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	c.Redirect(code, location)
+}
+
+// RedirectTemporary 方法用于临时重定向：GET 和 HEAD 请求使用 302，其他方法使用 307 以保留请求方法和请求体
+func (c *Context) RedirectTemporary(location string) {
+	code := http.StatusTemporaryRedirect
+	if c.Req.Method == http.MethodGet || c.Req.Method == http.MethodHead {
+		code = http.StatusFound
+	}
+	c.Redirect(code, location)
+}
+
+// SetCookie 方法用于设置响应 Cookie，value 会先进行 URL 转义
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// Cookie 方法用于读取请求中名为 name 的 Cookie 并进行 URL 反转义，Cookie 不存在时返回 http.ErrNoCookie
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+// addRouteCtx 方法用于注册以 *Context 为参数的处理函数
+func (r *router) addRouteCtx(method, pattern string, h func(*Context)) {
+	r.addRoute(method, pattern, func(w http.ResponseWriter, req *http.Request) {
+		h(newContext(w, req))
+	})
+}
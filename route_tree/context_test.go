@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextParamAndQuery(t *testing.T) {
+	r := newRouter()
+	r.addRouteCtx(http.MethodGet, "/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "id=%s sort=%s missing=%q", c.Param("id"), c.Query("sort"), c.Query("missing"))
+	})
+	// 原有的 http.HandlerFunc 注册方式保持可用
+	r.addRoute(http.MethodGet, "/plain", text("plain"))
+
+	w := serve(r, http.MethodGet, "/users/42?sort=name&sort=age")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if want := `id=42 sort=name missing=""`; w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if w := serve(r, http.MethodGet, "/plain"); w.Body.String() != "plain" {
+		t.Errorf("GET /plain: body = %q", w.Body.String())
+	}
+}
+
+func TestContextJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := newContext(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.JSON(http.StatusCreated, map[string]interface{}{"id": 1, "name": "geektutu"})
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want 201", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if want := `{"id":1,"name":"geektutu"}` + "\n"; w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestContextJSONEncodeError(t *testing.T) {
+	logs := captureLog(t)
+	w := httptest.NewRecorder()
+	c := newContext(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.JSON(http.StatusOK, map[string]interface{}{"ch": make(chan int)})
+
+	// 编码失败时只记录日志，不再写出第二个状态码
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+	if !strings.Contains(logs.String(), "json encode error") {
+		t.Errorf("encode error was not logged: %q", logs)
+	}
+}
+
+func TestContextBindJSON(t *testing.T) {
+	body := strings.NewReader(`{"name":"geektutu","age":20}`)
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", body))
+
+	var user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	if err := c.BindJSON(&user); err != nil {
+		t.Fatalf("BindJSON: %v", err)
+	}
+	if user.Name != "geektutu" || user.Age != 20 {
+		t.Errorf("user = %+v", user)
+	}
+
+	c = newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{")))
+	if err := c.BindJSON(&user); err == nil {
+		t.Error("BindJSON with a malformed body returned no error")
+	}
+}
+
+func TestContextQueryHelpers(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?page=3&q=go&bad=x", nil))
+
+	if got := c.Query("q"); got != "go" {
+		t.Errorf(`Query("q") = %q, want "go"`, got)
+	}
+	if got := c.QueryDefault("sort", "name"); got != "name" {
+		t.Errorf(`QueryDefault("sort") = %q, want the fallback "name"`, got)
+	}
+	if got := c.QueryDefault("q", "name"); got != "go" {
+		t.Errorf(`QueryDefault("q") = %q, want "go"`, got)
+	}
+	if n, err := c.QueryInt("page"); err != nil || n != 3 {
+		t.Errorf(`QueryInt("page") = %d, %v, want 3`, n, err)
+	}
+	if _, err := c.QueryInt("bad"); err == nil {
+		t.Error(`QueryInt("bad") returned no error`)
+	}
+
+	// 查询参数只在第一次访问时解析，之后修改 URL 不影响结果
+	c.Req.URL.RawQuery = "q=changed"
+	if got := c.Query("q"); got != "go" {
+		t.Errorf("Query after the first access = %q, want the cached value", got)
+	}
+}
+
+func TestContextNegotiate(t *testing.T) {
+	offers := []string{"application/json", "text/html"}
+	tests := []struct {
+		accept, want string
+	}{
+		{"text/html", "text/html"},
+		{"application/json;q=0.5, text/html;q=0.9", "text/html"},
+		{"text/*", "text/html"},
+		{"*/*", "application/json"},
+		// 更具体的范围优先：text/html 被 q=0 排除，不会因为 */* 而被选中
+		{"text/html;q=0, */*;q=0.1", "application/json"},
+		{"image/png", ""},
+		{"", "application/json"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		if got := newContext(httptest.NewRecorder(), req).Negotiate(offers...); got != tt.want {
+			t.Errorf("Accept %q: Negotiate = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestContextTypedParams(t *testing.T) {
+	r := newRouter()
+	var (
+		id     int
+		idErr  error
+		big    int64
+		active bool
+		page   string
+	)
+	r.addRouteCtx(http.MethodGet, "/items/:id/:big/:active", func(c *Context) {
+		id, idErr = c.ParamInt("id")
+		big, _ = c.ParamInt64("big")
+		active, _ = c.ParamBool("active")
+		page = c.ParamDefault("page", "1")
+	})
+
+	serve(r, http.MethodGet, "/items/42/9000000000/true")
+	if idErr != nil || id != 42 {
+		t.Errorf("ParamInt(id) = %d, %v, want 42, nil", id, idErr)
+	}
+	if big != 9000000000 {
+		t.Errorf("ParamInt64(big) = %d, want 9000000000", big)
+	}
+	if !active {
+		t.Errorf("ParamBool(active) = false, want true")
+	}
+	if page != "1" {
+		t.Errorf("ParamDefault(page) = %q, want fallback %q", page, "1")
+	}
+
+	serve(r, http.MethodGet, "/items/abc/1/false")
+	if idErr == nil {
+		t.Fatalf("ParamInt(id) with %q: want error", "abc")
+	}
+	if want := `path param "id": invalid integer "abc"`; idErr.Error() != want {
+		t.Errorf("error = %q, want %q", idErr, want)
+	}
+}
+
+// countingFlusher 记录 Flush 的调用次数，以及每次刷新时已写出的内容
+type countingFlusher struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (f *countingFlusher) Flush() {
+	f.flushed = append(f.flushed, f.Body.String())
+}
+
+// noFlushWriter 只实现 http.ResponseWriter，不支持 Flush 和 Hijack
+type noFlushWriter struct{ http.ResponseWriter }
+
+func TestContextSSE(t *testing.T) {
+	w := &countingFlusher{ResponseRecorder: httptest.NewRecorder()}
+	c := newContext(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if err := c.SSE("progress", map[string]int{"done": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SSE("", "line1\nline2"); err != nil {
+		t.Fatal(err)
+	}
+
+	first := "event: progress\ndata: {\"done\":1}\n\n"
+	want := []string{first, first + "data: line1\ndata: line2\n\n"}
+	if len(w.flushed) != len(want) {
+		t.Fatalf("flushed %d times, want %d", len(w.flushed), len(want))
+	}
+	for i := range want {
+		if w.flushed[i] != want[i] {
+			t.Errorf("flush %d: body = %q, want %q", i, w.flushed[i], want[i])
+		}
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	c = newContext(noFlushWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if err := c.SSE("progress", "x"); err == nil {
+		t.Error("SSE on a writer without Flush: want error")
+	}
+}
+
+// hijackWriter 是支持 Hijack 的假写入器，返回 net.Pipe 的一端
+type hijackWriter struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw := bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn))
+	return h.conn, rw, nil
+}
+
+func TestContextHijack(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	w := &hijackWriter{ResponseRecorder: httptest.NewRecorder(), conn: server}
+	c := newContext(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	conn, rw, err := c.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack() error = %v", err)
+	}
+	if conn != server || rw == nil {
+		t.Fatalf("Hijack() = %v, %v, want the writer's connection", conn, rw)
+	}
+
+	c = newContext(noFlushWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if _, _, err := c.Hijack(); err == nil || !strings.Contains(err.Error(), "does not support hijacking") {
+		t.Errorf("Hijack() on a non-hijacker: error = %v", err)
+	}
+}
+
+func TestContextHTML(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"hello.tmpl":  `{{define "hello"}}<p>Hello, {{.Name}}!</p>{{end}}`,
+		"broken.tmpl": `{{define "broken"}}before{{.Missing.Field}}{{end}}`,
+	})
+	r := newRouter()
+	r.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	r.addRouteCtx(http.MethodGet, "/hello/:name", func(c *Context) {
+		c.HTML(http.StatusOK, "hello", map[string]string{"Name": c.Param("name")})
+	})
+	r.addRouteCtx(http.MethodGet, "/broken", func(c *Context) {
+		c.HTML(http.StatusOK, "broken", map[string]interface{}{"Missing": nil})
+	})
+
+	w := serve(r, http.MethodGet, "/hello/<b>")
+	if w.Code != http.StatusOK || w.Body.String() != "<p>Hello, &lt;b&gt;!</p>" {
+		t.Errorf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// 响应头已经写出后的渲染错误只记录日志，不会再写一次错误响应
+	logs := captureLog(t)
+	w = serve(r, http.MethodGet, "/broken")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Errorf("render error: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if !strings.Contains(logs.String(), "html render error") {
+		t.Errorf("render error was not logged: %q", logs.String())
+	}
+}
+
+func TestContextCookies(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := newContext(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.SetCookie("session", "a b;c=d", 3600, "", "example.com", true, true)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Set-Cookie headers = %d, want 1", len(cookies))
+	}
+	got := cookies[0]
+	if got.Path != "/" || got.Domain != "example.com" || got.MaxAge != 3600 || !got.Secure || !got.HttpOnly {
+		t.Errorf("cookie attributes = %+v", got)
+	}
+
+	// 把响应中的 Cookie 带回下一次请求，读出的值应与设置的原值相同
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(got)
+	c = newContext(httptest.NewRecorder(), req)
+	if value, err := c.Cookie("session"); err != nil || value != "a b;c=d" {
+		t.Errorf("Cookie(session) = %q, %v, want %q", value, err, "a b;c=d")
+	}
+	if _, err := c.Cookie("missing"); err != http.ErrNoCookie {
+		t.Errorf("Cookie(missing) error = %v, want http.ErrNoCookie", err)
+	}
+}
+
+func TestContextRedirect(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := newContext(w, httptest.NewRequest(http.MethodGet, "/old", nil))
+	if err := c.Redirect(http.StatusFound, "/new"); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/new" {
+		t.Errorf("Redirect(302): status = %d, Location = %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	c = newContext(w, httptest.NewRequest(http.MethodGet, "/old", nil))
+	if err := c.Redirect(http.StatusOK, "/new"); err == nil {
+		t.Error("Redirect(200): want error")
+	}
+	if w.Header().Get("Location") != "" {
+		t.Errorf("Redirect(200) wrote Location = %q", w.Header().Get("Location"))
+	}
+
+	tests := []struct {
+		method    string
+		permanent bool
+		code      int
+	}{
+		{http.MethodGet, true, http.StatusMovedPermanently},
+		{http.MethodPost, true, http.StatusPermanentRedirect},
+		{http.MethodGet, false, http.StatusFound},
+		{http.MethodPost, false, http.StatusTemporaryRedirect},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c := newContext(w, httptest.NewRequest(tt.method, "/old", nil))
+		if tt.permanent {
+			c.RedirectPermanent("/new")
+		} else {
+			c.RedirectTemporary("/new")
+		}
+		if w.Code != tt.code {
+			t.Errorf("%s permanent=%v: status = %d, want %d", tt.method, tt.permanent, w.Code, tt.code)
+		}
+	}
+}
+
+func TestContextSetGet(t *testing.T) {
+	r := newRouter()
+	r.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			newContext(w, req).Set("user", "alice")
+			next(w, req)
+		}
+	})
+	r.addRouteCtx(http.MethodGet, "/me", func(c *Context) {
+		user, ok := c.Get("user")
+		_, missing := c.Get("role")
+		c.String(http.StatusOK, "user=%v ok=%v role=%v", user, ok, missing)
+	})
+
+	if w := serve(r, http.MethodGet, "/me"); w.Body.String() != "user=alice ok=true role=false" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+
+	// 没有调用 Set 的请求不创建存储
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if _, ok := c.Get("user"); ok {
+		t.Error("Get on an empty context: ok = true")
+	}
+	if c.getState().keys != nil {
+		t.Error("Get allocated the key/value store")
+	}
+}
+
+func TestContextLookup(t *testing.T) {
+	r := newRouter()
+	r.addRouteCtx(http.MethodGet, "/items/:id", func(c *Context) {
+		c.String(http.StatusOK, "id=%s sort=%s path=%s query=%s missing=%q",
+			c.Lookup("id"), c.Lookup("sort"), c.PathParam("id"), c.QueryParam("id"), c.Lookup("missing"))
+	})
+
+	w := serve(r, http.MethodGet, "/items/7?sort=asc&id=99")
+	// 路径和查询参数中都有 id 时路由参数优先，PathParam 和 QueryParam 分别取各自来源的值
+	if want := `id=7 sort=asc path=7 query=99 missing=""`; w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestContextStream(t *testing.T) {
+	w := &countingFlusher{ResponseRecorder: httptest.NewRecorder()}
+	c := newContext(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	chunks := []string{"one\n", "two\n", "three\n"}
+	i := 0
+	disconnected := c.Stream(func(out io.Writer) bool {
+		io.WriteString(out, chunks[i])
+		i++
+		return i < len(chunks)
+	})
+	if disconnected {
+		t.Error("Stream returned true without a disconnect")
+	}
+	want := []string{"one\n", "one\ntwo\n", "one\ntwo\nthree\n"}
+	if !reflect.DeepEqual(w.flushed, want) {
+		t.Errorf("flushed = %q, want %q", w.flushed, want)
+	}
+
+	// 客户端断开后不再调用 step
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(ctx)
+	c = newContext(httptest.NewRecorder(), req)
+	steps := 0
+	disconnected = c.Stream(func(out io.Writer) bool {
+		steps++
+		if steps == 2 {
+			cancel()
+		}
+		return true
+	})
+	if !disconnected || steps != 2 {
+		t.Errorf("cancelled stream: disconnected = %v, steps = %d, want true, 2", disconnected, steps)
+	}
+}
+
+func TestContextDoneOnClientDisconnect(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	r := newRouter()
+	r.addRouteCtx(http.MethodGet, "/wait", func(c *Context) {
+		close(started)
+		select {
+		case <-c.Done():
+			cancelled <- c.Req.Context().Err()
+		case <-time.After(5 * time.Second):
+			cancelled <- nil
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/wait", nil)
+	go func() {
+		<-started
+		cancel()
+	}()
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("request completed, want it cancelled")
+	}
+
+	if err := <-cancelled; err != context.Canceled {
+		t.Errorf("handler context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestContextNotFound(t *testing.T) {
+	r := newRouter()
+	r.NotFound(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "branded 404")
+	}))
+	var after bool
+	r.UseContext(func(c *Context) {
+		c.Next()
+		after = true
+	})
+	r.addRouteCtx(http.MethodGet, "/users/:id", func(c *Context) {
+		if c.Param("id") != "1" {
+			c.NotFound()
+			return
+		}
+		c.String(http.StatusOK, "user 1")
+	})
+	r.addRouteCtx(http.MethodGet, "/teapot", func(c *Context) {
+		c.AbortWithStatus(http.StatusTeapot)
+	})
+
+	w := serve(r, http.MethodGet, "/users/2")
+	if w.Code != http.StatusNotFound || w.Body.String() != "branded 404" {
+		t.Errorf("c.NotFound(): status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if !after {
+		t.Error("middleware did not resume after c.NotFound()")
+	}
+	if w := serve(r, http.MethodGet, "/users/1"); w.Body.String() != "user 1" {
+		t.Errorf("existing user: body = %q", w.Body.String())
+	}
+	if w := serve(r, http.MethodGet, "/teapot"); w.Code != http.StatusTeapot || w.Body.Len() != 0 {
+		t.Errorf("AbortWithStatus: status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSOptions 结构体用于配置 CORS 中间件
+type CORSOptions struct {
+	AllowedOrigins   []string // 允许的来源，包含 "*" 时允许任意来源
+	AllowedMethods   []string // 预检请求允许的方法，为空时使用 GET、POST、PUT、PATCH、DELETE、HEAD
+	AllowedHeaders   []string // 预检请求允许的请求头，为空时原样返回预检请求中声明的请求头
+	AllowCredentials bool     // 是否允许携带 Cookie 等凭据
+	MaxAge           int      // 预检结果的缓存秒数，为 0 时不设置
+}
+
+// CORS 中间件用于为跨域请求设置 Access-Control-* 响应头，并以 204 直接响应预检请求。
+// 只有来源在允许列表中时才会设置这些响应头；允许携带凭据时总是回显请求的 Origin，而不是 "*"
+func CORS(opts CORSOptions) Middleware {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead}
+	}
+	allowAll := false
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			if origin == "" || !(allowAll || originAllowed(opts.AllowedOrigins, origin)) {
+				next(w, req)
+				return
+			}
+
+			if allowAll && !opts.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			// 预检请求：OPTIONS 且带有 Access-Control-Request-Method 头
+			if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+				header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+				if len(opts.AllowedHeaders) > 0 {
+					header.Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+				} else if requested := req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+					header.Set("Access-Control-Allow-Headers", requested)
+				}
+				if opts.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next(w, req)
+		}
+	}
+}
+
+// originAllowed 函数用于判断 origin 是否在允许列表中，比较时忽略大小写
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveCORS 函数用于发送一个带有 Origin 头的请求，headers 中的其他请求头一并设置
+func serveCORS(r *router, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/api", nil)
+	req.Header.Set("Origin", origin)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	r := newRouter()
+	r.Use(CORS(CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}))
+	r.GET("/api", text("data"))
+
+	w := serveCORS(r, http.MethodGet, "https://app.example.com", nil)
+	if w.Body.String() != "data" {
+		t.Fatalf("allowed origin: body = %q", w.Body.String())
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("allowed origin: Access-Control-Allow-Origin = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("allowed origin: Access-Control-Allow-Credentials = %q", got)
+	}
+
+	w = serveCORS(r, http.MethodGet, "https://evil.example.com", nil)
+	if w.Body.String() != "data" {
+		t.Fatalf("disallowed origin: body = %q", w.Body.String())
+	}
+	for _, key := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials"} {
+		if got := w.Header().Get(key); got != "" {
+			t.Errorf("disallowed origin: %s = %q, want unset", key, got)
+		}
+	}
+
+	w = serveCORS(r, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method": http.MethodPut,
+	})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight: status = %d, want 204", w.Code)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE, HEAD",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Max-Age":       "600",
+	}
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("preflight: %s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	r := newRouter()
+	r.Use(CORS(CORSOptions{AllowedOrigins: []string{"*"}}))
+	r.GET("/api", text("data"))
+
+	w := serveCORS(r, http.MethodGet, "https://any.example.com", nil)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+}
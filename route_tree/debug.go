@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DumpTree 方法用于以缩进文本的形式输出 method 对应的路由树，便于排查路由为什么没有匹配。
+// 每行一个节点，显示节点的 part，通配符节点标记 (wild)，注册了路由规则的节点在 -> 后显示完整规则。
+// 同一层的子节点按匹配时尝试的顺序输出，与注册顺序无关
+func (r *router) DumpTree(method string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	root, ok := r.roots[strings.ToUpper(method)]
+	if !ok {
+		return ""
+	}
+	var b strings.Builder
+	root.dump(&b, 0)
+	return b.String()
+}
+
+// dump 方法用于将以当前节点为根的子树写入 b，depth 为当前节点的深度
+func (n *node) dump(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	if depth == 0 {
+		b.WriteString("/")
+	} else {
+		b.WriteString(n.part)
+	}
+	if n.isWild {
+		b.WriteString(" (wild)")
+	}
+	if n.pattern != "" {
+		b.WriteString(" -> ")
+		b.WriteString(n.pattern)
+	}
+	b.WriteString("\n")
+
+	for _, child := range n.children {
+		child.dump(b, depth+1)
+	}
+}
+
+// ToDOT 方法用于将所有请求方法的路由树导出为 Graphviz 的 digraph，可以通过 dot -Tpng 生成路由图。
+// 节点以 part 作为标签，注册了路由规则的节点会加粗并显示完整规则。
+// 节点 ID 按请求方法排序后的遍历顺序分配，因此路由不变时多次导出的结果一致
+func (r *router) ToDOT() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	methods := make([]string, 0, len(r.roots))
+	for method := range r.roots {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var b strings.Builder
+	b.WriteString("digraph routes {\n")
+	b.WriteString("  node [shape=box];\n")
+	id := 0
+	for _, method := range methods {
+		root := fmt.Sprintf("n%d", id)
+		id++
+		fmt.Fprintf(&b, "  %s [label=%s, shape=ellipse];\n", root, strconv.Quote(method))
+		for _, child := range r.roots[method].dotChildren() {
+			child.dot(&b, root, &id)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotChildren 方法用于返回导出 DOT 时根节点下需要展开的节点，注册了 / 的根节点本身也作为一个节点输出
+func (n *node) dotChildren() []*node {
+	if n.pattern != "" {
+		return []*node{n}
+	}
+	return n.children
+}
+
+// dot 方法用于将当前节点及其子树写入 b，并从 parent 连一条边指向当前节点，id 为下一个可用的节点编号
+func (n *node) dot(b *strings.Builder, parent string, id *int) {
+	name := fmt.Sprintf("n%d", *id)
+	*id++
+
+	label := n.part
+	if label == "" {
+		label = "/"
+	}
+	if n.pattern != "" {
+		fmt.Fprintf(b, "  %s [label=%s, style=bold];\n", name, strconv.Quote(label+"\n"+n.pattern))
+	} else {
+		fmt.Fprintf(b, "  %s [label=%s];\n", name, strconv.Quote(label))
+	}
+	fmt.Fprintf(b, "  %s -> %s;\n", parent, name)
+
+	for _, child := range n.children {
+		child.dot(b, name, id)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// debugRoutes 函数用于按 order 指定的顺序注册一组用于导出路由树的路由
+func debugRoutes(order []int) *router {
+	routes := []struct{ method, pattern string }{
+		{http.MethodGet, "/"},
+		{http.MethodGet, "/users"},
+		{http.MethodGet, "/users/:id"},
+		{http.MethodGet, "/users/:id/posts"},
+		{http.MethodGet, "/static/*filepath"},
+		{http.MethodPost, "/users"},
+	}
+	r := newRouter()
+	for _, i := range order {
+		r.addRoute(routes[i].method, routes[i].pattern, text("x"))
+	}
+	return r
+}
+
+func TestDumpTree(t *testing.T) {
+	r := debugRoutes([]int{0, 1, 2, 3, 4, 5})
+	want := `/ -> /
+  static
+    *filepath (wild) -> /static/*filepath
+  users -> /users
+    :id (wild) -> /users/:id
+      posts -> /users/:id/posts
+`
+	if got := r.DumpTree(http.MethodGet); got != want {
+		t.Errorf("DumpTree(GET) =\n%s\nwant\n%s", got, want)
+	}
+	if got := r.DumpTree(http.MethodDelete); got != "" {
+		t.Errorf("DumpTree(DELETE) = %q, want empty", got)
+	}
+
+	// 输出与注册顺序无关
+	if got := debugRoutes([]int{5, 4, 3, 2, 1, 0}).DumpTree(http.MethodGet); got != want {
+		t.Errorf("DumpTree(GET) after reverse registration =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestToDOT(t *testing.T) {
+	r := debugRoutes([]int{0, 1, 2, 3, 4, 5})
+	dot := r.ToDOT()
+
+	want := []string{
+		"digraph routes {\n",
+		`n0 [label="GET", shape=ellipse];`,
+		`n2 [label="static"];`,
+		`n3 [label="*filepath\n/static/*filepath", style=bold];`,
+		`n5 [label=":id\n/users/:id", style=bold];`,
+		"n4 -> n5;",
+		`n7 [label="POST", shape=ellipse];`,
+		`n8 [label="users\n/users", style=bold];`,
+		"n7 -> n8;",
+	}
+	for _, s := range want {
+		if !strings.Contains(dot, s) {
+			t.Errorf("ToDOT() missing %q in\n%s", s, dot)
+		}
+	}
+	if again := r.ToDOT(); again != dot {
+		t.Errorf("ToDOT() is not stable across calls:\n%s\nvs\n%s", dot, again)
+	}
+}
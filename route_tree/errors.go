@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError 结构体表示一个带状态码的错误，处理函数返回它时默认的错误处理会以 Code 和 Msg 作为响应
+type HTTPError struct {
+	Code int    // 响应状态码
+	Msg  string // 响应内容，为空时使用状态码对应的标准描述
+}
+
+func (e *HTTPError) Error() string {
+	if e.Msg == "" {
+		return http.StatusText(e.Code)
+	}
+	return e.Msg
+}
+
+// ErrorHandler 方法用于设置处理函数返回错误时的处理逻辑，例如统一将错误渲染为 JSON
+func (r *router) ErrorHandler(handler func(*Context, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onError = handler
+}
+
+// handleError 方法用于处理处理函数返回的错误，优先使用自定义的错误处理函数
+func (r *router) handleError(c *Context, err error) {
+	r.mu.RLock()
+	handler := r.onError
+	r.mu.RUnlock()
+	if handler == nil {
+		handler = defaultErrorHandler
+	}
+	handler(c, err)
+}
+
+// defaultErrorHandler 函数是默认的错误处理：HTTPError 按其状态码和内容响应，其他错误返回 500，
+// 不把内部错误信息暴露给客户端
+func defaultErrorHandler(c *Context, err error) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		http.Error(c, httpErr.Error(), httpErr.Code)
+		return
+	}
+	http.Error(c, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// addRouteErr 方法用于注册返回 error 的处理函数，返回的错误交给 ErrorHandler 设置的错误处理函数
+func (r *router) addRouteErr(method, pattern string, h func(*Context) error) {
+	r.addRouteCtx(method, pattern, func(c *Context) {
+		if err := h(c); err != nil {
+			r.handleError(c, err)
+		}
+	})
+}
+
+// GETErr 方法用于注册返回 error 的 GET 请求处理函数，处理函数无需自己写出错误响应
+func (r *router) GETErr(pattern string, h func(*Context) error) {
+	r.addRouteErr(http.MethodGet, pattern, h)
+}
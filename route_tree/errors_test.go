@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestErrorHandlers(t *testing.T) {
+	r := newRouter()
+	r.GETErr("/items/:id", func(c *Context) error {
+		return &HTTPError{Code: http.StatusNotFound, Msg: "item " + c.Param("id") + " not found"}
+	})
+	r.GETErr("/wrapped", func(c *Context) error {
+		return fmt.Errorf("load: %w", &HTTPError{Code: http.StatusConflict})
+	})
+	r.GETErr("/boom", func(c *Context) error {
+		return errors.New("database password is hunter2")
+	})
+	r.GETErr("/ok", func(c *Context) error {
+		c.String(http.StatusOK, "ok")
+		return nil
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/items/7", http.StatusNotFound, "item 7 not found\n"},
+		{"/wrapped", http.StatusConflict, "Conflict\n"},
+		// 其他错误返回 500，不暴露内部错误信息
+		{"/boom", http.StatusInternalServerError, "Internal Server Error\n"},
+		{"/ok", http.StatusOK, "ok"},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("GET %s: status = %d, body = %q, want %d, %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+
+	// 自定义的错误处理函数替换默认行为
+	r.ErrorHandler(func(c *Context, err error) {
+		c.JSON(http.StatusTeapot, map[string]string{"error": err.Error()})
+	})
+	w := serve(r, http.MethodGet, "/boom")
+	if w.Code != http.StatusTeapot || w.Body.String() != "{\"error\":\"database password is hunter2\"}\n" {
+		t.Errorf("custom handler: status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import "net/http"
+
+// RouteGroup 结构体表示一组共享相同路径前缀的路由
+type RouteGroup struct {
+	prefix      string       // 路由组的完整前缀，包含所有父级路由组的前缀
+	parent      *RouteGroup  // 父级路由组，顶层路由组为 nil
+	middlewares []Middleware // 只作用于本路由组（及其子路由组）下路由的中间件
+	router      *router      // 所有路由组共享同一个路由树和处理函数表
+}
+
+// Group 方法用于创建一个以 prefix 为前缀的路由组
+func (r *router) Group(prefix string) *RouteGroup {
+	return &RouteGroup{
+		prefix: prefix,
+		router: r,
+	}
+}
+
+// Group 方法用于在当前路由组下创建嵌套的路由组，新路由组的前缀会拼接在当前前缀之后
+func (g *RouteGroup) Group(prefix string) *RouteGroup {
+	return &RouteGroup{
+		prefix: g.prefix + prefix,
+		parent: g,
+		router: g.router,
+	}
+}
+
+// Use 方法用于注册路由组中间件，它们只会作用于该路由组及其子路由组下的路由
+func (g *RouteGroup) Use(mw ...Middleware) {
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+	g.middlewares = append(g.middlewares, mw...)
+}
+
+// addRoute 方法用于在路由组下注册路由，实际注册的路由规则为前缀与 pattern 的拼接
+func (g *RouteGroup) addRoute(method, pattern string, handler http.HandlerFunc) *Route {
+	pattern = g.prefix + pattern
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+	key := g.router.insertRoute(method, pattern, handler)
+	g.router.groups[key] = g
+	return &Route{Method: method, Pattern: pattern, Handler: handler, router: g.router}
+}
+
+// GET 方法用于在路由组下注册 GET 请求的路由
+func (g *RouteGroup) GET(pattern string, handler http.HandlerFunc) *Route {
+	return g.addRoute(http.MethodGet, pattern, handler)
+}
+
+// POST 方法用于在路由组下注册 POST 请求的路由
+func (g *RouteGroup) POST(pattern string, handler http.HandlerFunc) *Route {
+	return g.addRoute(http.MethodPost, pattern, handler)
+}
+
+// PUT 方法用于在路由组下注册 PUT 请求的路由
+func (g *RouteGroup) PUT(pattern string, handler http.HandlerFunc) *Route {
+	return g.addRoute(http.MethodPut, pattern, handler)
+}
+
+// DELETE 方法用于在路由组下注册 DELETE 请求的路由
+func (g *RouteGroup) DELETE(pattern string, handler http.HandlerFunc) *Route {
+	return g.addRoute(http.MethodDelete, pattern, handler)
+}
+
+// PATCH 方法用于在路由组下注册 PATCH 请求的路由
+func (g *RouteGroup) PATCH(pattern string, handler http.HandlerFunc) *Route {
+	return g.addRoute(http.MethodPatch, pattern, handler)
+}
+
+// allMiddlewares 方法用于按从外到内的顺序收集父级路由组和当前路由组的中间件
+func (g *RouteGroup) allMiddlewares() []Middleware {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.allMiddlewares(), g.middlewares...)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNestedGroupPrefixes(t *testing.T) {
+	r := newRouter()
+	api := r.Group("/api/v1")
+	admin := api.Group("/admin")
+	api.addRoute(http.MethodGet, "/users", text("users"))
+	admin.addRoute(http.MethodGet, "/stats", text("stats"))
+
+	for _, key := range []string{"GET-/api/v1/users", "GET-/api/v1/admin/stats"} {
+		if r.handlers[key] == nil {
+			t.Errorf("handler %q not registered", key)
+		}
+	}
+	if w := serve(r, http.MethodGet, "/api/v1/admin/stats"); w.Body.String() != "stats" {
+		t.Errorf("GET /api/v1/admin/stats: body = %q, want stats", w.Body.String())
+	}
+}
+
+func TestGroupMiddlewareScope(t *testing.T) {
+	r := newRouter()
+	var calls []string
+	r.Use(record(&calls, "global"))
+	api := r.Group("/api")
+	api.Use(record(&calls, "api"))
+	v1 := api.Group("/v1")
+	v1.Use(record(&calls, "v1"))
+
+	r.addRoute(http.MethodGet, "/", func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "root")
+	})
+	v1.addRoute(http.MethodGet, "/users", func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "users")
+	})
+
+	serve(r, http.MethodGet, "/")
+	if want := []string{"global", "root"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("root route: calls = %v, want %v", calls, want)
+	}
+
+	// 父级路由组的中间件同样作用于子路由组，并且位于子路由组中间件之外
+	calls = nil
+	serve(r, http.MethodGet, "/api/v1/users")
+	if want := []string{"global", "api", "v1", "users"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("group route: calls = %v, want %v", calls, want)
+	}
+}
+
+func TestGroupMethodHelpers(t *testing.T) {
+	r := newRouter()
+	g := r.Group("/api")
+	helpers := map[string]func(string, http.HandlerFunc) *Route{
+		http.MethodGet:    g.GET,
+		http.MethodPost:   g.POST,
+		http.MethodPut:    g.PUT,
+		http.MethodDelete: g.DELETE,
+		http.MethodPatch:  g.PATCH,
+	}
+	for method, register := range helpers {
+		if route := register("/res", text(method)); route.Pattern != "/api/res" {
+			t.Errorf("%s helper returned pattern %q, want /api/res", method, route.Pattern)
+		}
+		if r.handlers[method+"-/api/res"] == nil {
+			t.Errorf("%s helper did not register under %q", method, method+"-/api/res")
+		}
+	}
+}
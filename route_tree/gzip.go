@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// defaultGzipMinLength 是 Gzip 中间件默认的最小压缩长度，更小的响应压缩收益不大
+const defaultGzipMinLength = 1024
+
+// Gzip 中间件用于在客户端支持 gzip 时压缩响应体，小于 1KB 的响应不压缩
+func Gzip() Middleware {
+	return GzipWithMinLength(defaultGzipMinLength)
+}
+
+// GzipWithMinLength 中间件与 Gzip 相同，但可以指定最小压缩长度 minLength。
+// 处理函数已经设置了 Content-Encoding 的响应（已压缩的内容）不会被重复压缩
+func GzipWithMinLength(minLength int) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if !acceptsGzip(req) {
+				next(w, req)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			gw := &gzipResponseWriter{ResponseWriter: w, minLength: minLength}
+			defer gw.finish()
+			next(gw, req)
+		}
+	}
+}
+
+// acceptsGzip 函数用于判断请求的 Accept-Encoding 头中是否包含 gzip
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter 在响应体达到最小压缩长度之前先缓存写入的数据，
+// 达到长度后再决定是否压缩，这样小响应可以原样写出
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minLength int          // 最小压缩长度
+	status    int          // 处理函数设置的状态码，写出响应头之前暂存
+	buf       bytes.Buffer // 决定是否压缩之前缓存的响应体
+	decided   bool         // 是否已经决定压缩与否并写出了响应头
+	gz        *gzip.Writer // 压缩写入器，不压缩时为 nil
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf.Write(p)
+		if w.buf.Len() < w.minLength {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide 方法用于写出响应头并确定是否压缩，然后把缓存的数据写出
+func (w *gzipResponseWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		compress = false
+	}
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf.Bytes())
+	} else if w.buf.Len() > 0 {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// Flush 方法用于立即写出缓存和已压缩的数据，流式响应（例如 SSE）需要调用它
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// finish 方法在处理函数返回后调用，写出仍在缓存中的小响应，或者结束 gzip 流
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		if w.status == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
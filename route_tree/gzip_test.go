@@ -0,0 +1,62 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	large := strings.Repeat("hello gzip ", 200)
+	r := newRouter()
+	r.Use(GzipWithMinLength(512))
+	r.GET("/large", text(large))
+	r.GET("/small", text("tiny"))
+	r.GET("/compressed", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, large)
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/large", "gzip, deflate")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("gzip client: Content-Encoding = %q, want gzip", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != large {
+		t.Errorf("decompressed body has %d bytes, want %d", len(body), len(large))
+	}
+
+	tests := []struct {
+		name, path, acceptEncoding, encoding, body string
+	}{
+		{"no gzip support", "/large", "", "", large},
+		{"gzip refused", "/large", "gzip;q=0", "", large},
+		{"below threshold", "/small", "gzip", "", "tiny"},
+		{"already compressed", "/compressed", "gzip", "br", large},
+	}
+	for _, tt := range tests {
+		w := get(tt.path, tt.acceptEncoding)
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s: Content-Encoding = %q, want %q", tt.name, got, tt.encoding)
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: body was modified", tt.name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// readyTimeout 是 Ready 注册的就绪检查接口等待所有检查完成的最长时间
+const readyTimeout = 5 * time.Second
+
+// healthResponse 结构体是存活和就绪检查接口返回的 JSON 响应
+type healthResponse struct {
+	Status string   `json:"status"`
+	Failed []string `json:"failed,omitempty"` // 未通过的检查及其错误信息
+}
+
+// Health 方法用于在 path 上注册存活检查接口，只要服务能处理请求就返回 200，适合作为 Kubernetes 的 livenessProbe
+func (r *router) Health(path string) {
+	r.addRouteCtx(http.MethodGet, path, func(c *Context) {
+		c.JSON(http.StatusOK, healthResponse{Status: "ok"})
+	})
+}
+
+// ReadyCheck 结构体描述一项就绪检查，Name 会出现在失败检查的报告中，用于定位出问题的依赖
+type ReadyCheck struct {
+	Name  string       // 检查名称，例如 "database"，同一个接口中不能为空也不能重复
+	Check func() error // 检查函数，返回非 nil 的错误表示未就绪
+}
+
+// Ready 方法用于在 path 上注册就绪检查接口，适合作为 Kubernetes 的 readinessProbe，例如
+// r.Ready("/readyz", ReadyCheck{Name: "database", Check: db.Ping})。
+// 每次请求时并发执行所有 checks，全部通过返回 200；有检查返回错误或在 readyTimeout 内没有完成时返回 503，
+// 并在 JSON 响应的 failed 字段中按 "名称: 错误" 的格式列出失败的检查。检查名称为空或重复时 panic
+func (r *router) Ready(path string, checks ...ReadyCheck) {
+	seen := make(map[string]bool, len(checks))
+	for i, check := range checks {
+		if check.Name == "" {
+			panic(fmt.Sprintf("ready check %d on '%s' has no name", i, path))
+		}
+		if seen[check.Name] {
+			panic(fmt.Sprintf("duplicate ready check '%s' on '%s'", check.Name, path))
+		}
+		seen[check.Name] = true
+	}
+
+	r.addRouteCtx(http.MethodGet, path, func(c *Context) {
+		failed := runChecks(c.Req.Context(), checks)
+		if len(failed) > 0 {
+			c.JSON(http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Failed: failed})
+			return
+		}
+		c.JSON(http.StatusOK, healthResponse{Status: "ok"})
+	})
+}
+
+// runChecks 函数用于并发执行 checks，返回失败检查的名称和错误信息，按检查注册的顺序排列。
+// 超时后不再等待尚未完成的检查，它们会被记为超时
+func runChecks(ctx context.Context, checks []ReadyCheck) []string {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	// 使用带缓冲的通道，超时后仍在执行的检查也能写入结果并退出
+	results := make([]chan error, len(checks))
+	for i, check := range checks {
+		results[i] = make(chan error, 1)
+		go func(check func() error, result chan<- error) {
+			result <- check()
+		}(check.Check, results[i])
+	}
+
+	var failed []string
+	for i, result := range results {
+		select {
+		case err := <-result:
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", checks[i].Name, err))
+			}
+		case <-ctx.Done():
+			failed = append(failed, fmt.Sprintf("%s: %v", checks[i].Name, ctx.Err()))
+		}
+	}
+	return failed
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHealthAndReady(t *testing.T) {
+	// 两个检查互相等待对方开始执行，只有并发执行时才能都通过
+	started := [2]chan struct{}{make(chan struct{}), make(chan struct{})}
+	waitFor := func(self, other int) func() error {
+		return func() error {
+			close(started[self])
+			<-started[other]
+			return nil
+		}
+	}
+
+	r := newRouter()
+	r.Health("/healthz")
+	r.Ready("/readyz", ReadyCheck{"first", waitFor(0, 1)}, ReadyCheck{"second", waitFor(1, 0)})
+	r.Ready("/readyz-db",
+		ReadyCheck{"cache", func() error { return nil }},
+		ReadyCheck{"database", func() error { return errors.New("connection refused") }},
+	)
+
+	tests := []struct {
+		path string
+		code int
+		want healthResponse
+	}{
+		{"/healthz", http.StatusOK, healthResponse{Status: "ok"}},
+		{"/readyz", http.StatusOK, healthResponse{Status: "ok"}},
+		{"/readyz-db", http.StatusServiceUnavailable, healthResponse{
+			Status: "unavailable",
+			Failed: []string{"database: connection refused"},
+		}},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, w.Code, tt.code)
+			continue
+		}
+		var got healthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s: body = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestReadyCheckNames(t *testing.T) {
+	r := newRouter()
+	ok := func() error { return nil }
+	tests := []struct {
+		checks []ReadyCheck
+		want   string
+	}{
+		{[]ReadyCheck{{"db", ok}, {"", ok}}, "ready check 1 on '/readyz' has no name"},
+		{[]ReadyCheck{{"db", ok}, {"db", ok}}, "duplicate ready check 'db' on '/readyz'"},
+	}
+	for _, tt := range tests {
+		if msg := panicMessage(func() { r.Ready("/readyz", tt.checks...) }); msg != tt.want {
+			t.Errorf("panic = %q, want %q", msg, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Host 方法用于获取只对指定主机名生效的路由，例如 r.Host("api.example.com").GET("/users", h)。
+// 主机名中的标签可以是参数，例如 r.Host(":tenant.example.com") 匹配 acme.example.com，
+// 并在路由参数中加入 tenant=acme；以 * 开头的标签匹配剩余的任意多级子域名。
+// 每个主机名拥有独立的路由树，请求的 Host 头匹配且路径在该主机下存在时优先使用，
+// 否则回退到不区分主机的默认路由。精确的主机名优先于带参数的主机名。
+// 主机路由在第一次调用时创建，其选项需要单独设置
+func (r *router) Host(host string) *router {
+	host = strings.ToLower(host)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hosts == nil {
+		r.hosts = make(map[string]*router)
+		r.hostTree = &node{}
+	}
+	hr, ok := r.hosts[host]
+	if !ok {
+		parts := hostParts(host)
+		if err := r.hostTree.conflict(host, parts, 0); err != nil {
+			panic(err.Error())
+		}
+		r.hostTree.insert(host, parts, 0)
+		hr = newRouter()
+		r.hosts[host] = hr
+	}
+	return hr
+}
+
+// hostParts 函数用于将主机名按 . 切分并倒序排列，例如 api.example.com 切分为 com、example、api，
+// 这样主机树与路由树一样从最不具体的部分开始匹配，共享相同的父域名
+func hostParts(host string) []string {
+	labels := strings.Split(host, ".")
+	parts := make([]string, 0, len(labels))
+	for i := len(labels) - 1; i >= 0; i-- {
+		if labels[i] != "" {
+			parts = append(parts, labels[i])
+		}
+	}
+	return parts
+}
+
+// hostRouter 方法用于根据请求的 Host 头查找能处理该路径的主机路由，同时返回主机名中捕获的参数，找不到时返回 nil
+func (r *router) hostRouter(req *http.Request, path string) (*router, map[string]string) {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	parts := hostParts(strings.ToLower(host))
+
+	r.mu.RLock()
+	var hr *router
+	var pattern string
+	if r.hostTree != nil {
+		if n := r.hostTree.search(parts, parts, 0, false); n != nil {
+			pattern = n.pattern
+			hr = r.hosts[pattern]
+		}
+	}
+	r.mu.RUnlock()
+	if hr == nil || len(hr.allowedMethods(path)) == 0 {
+		return nil, nil
+	}
+	return hr, hostParams(pattern, parts)
+}
+
+// hostParams 函数用于按主机名规则从倒序的主机名标签中取出参数，规则中没有参数时返回 nil
+func hostParams(pattern string, parts []string) map[string]string {
+	var params map[string]string
+	for i, part := range hostParts(pattern) {
+		switch part[0] {
+		case ':':
+			if params == nil {
+				params = make(map[string]string)
+			}
+			name, _ := splitParam(part)
+			params[name] = parts[i]
+		case '*':
+			if params == nil {
+				params = make(map[string]string)
+			}
+			// 通配符匹配的多级子域名按原来的顺序拼接，例如 a.b.example.com 中的 a.b
+			rest := make([]string, 0, len(parts)-i)
+			for j := len(parts) - 1; j >= i; j-- {
+				rest = append(rest, parts[j])
+			}
+			params[part[1:]] = strings.Join(rest, ".")
+			return params
+		}
+	}
+	return params
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveHost 函数用于向路由发送一个带有指定 Host 头的 GET 请求
+func serveHost(r *router, host, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = host
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHostRouting(t *testing.T) {
+	r := newRouter()
+	r.Host("api.example.com").GET("/users", text("api users"))
+	r.GET("/users", text("default users"))
+	r.GET("/about", text("about"))
+
+	tests := []struct {
+		host, path, body string
+	}{
+		{"api.example.com", "/users", "api users"},
+		{"API.Example.com:8080", "/users", "api users"},
+		{"www.example.com", "/users", "default users"},
+		// 主机路由中不存在的路径回退到默认路由
+		{"api.example.com", "/about", "about"},
+	}
+	for _, tt := range tests {
+		if w := serveHost(r, tt.host, tt.path); w.Body.String() != tt.body {
+			t.Errorf("GET %s%s: status = %d, body = %q, want %q", tt.host, tt.path, w.Code, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestHostParams(t *testing.T) {
+	r := newRouter()
+	tenantHandler := func(w http.ResponseWriter, req *http.Request) {
+		c := newContext(w, req)
+		w.Write([]byte("tenant=" + c.Param("tenant") + " id=" + c.Param("id")))
+	}
+	r.Host(":tenant.example.com").GET("/users/:id", tenantHandler)
+	// 精确的主机名优先于带参数的主机名，与注册顺序无关
+	r.Host("admin.example.com").GET("/users/:id", text("admin"))
+	r.Host("*sub.cdn.example.com").GET("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("sub=" + newContext(w, req).Param("sub")))
+	})
+
+	tests := []struct {
+		host, path, body string
+	}{
+		{"acme.example.com", "/users/7", "tenant=acme id=7"},
+		{"admin.example.com", "/users/7", "admin"},
+		{"a.b.cdn.example.com", "/", "sub=a.b"},
+	}
+	for _, tt := range tests {
+		if w := serveHost(r, tt.host, tt.path); w.Body.String() != tt.body {
+			t.Errorf("GET %s%s: status = %d, body = %q, want %q", tt.host, tt.path, w.Code, w.Body.String(), tt.body)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// overridableMethods 是 MethodOverride 允许改写成的 HTTP 方法
+var overridableMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// MethodOverride 中间件用于让只能发送 GET 和 POST 的客户端通过 POST 请求模拟 PUT、PATCH 和 DELETE：
+// 优先读取 X-HTTP-Method-Override 头，其次读取 application/x-www-form-urlencoded 表单中的 _method 字段。
+// 只改写 POST 请求，且只允许改写为 overridableMethods 中的方法，其他取值会被忽略。
+// 由于需要在路由匹配之前改写方法，它应当包裹整个路由，而不是通过 Use 注册，
+// 例如 http.ListenAndServe(addr, MethodOverride()(r.ServeHTTP))
+func MethodOverride() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodPost {
+				method := req.Header.Get("X-HTTP-Method-Override")
+				if method == "" {
+					if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+						method = req.PostFormValue("_method")
+					}
+				}
+				if method = strings.ToUpper(strings.TrimSpace(method)); overridableMethods[method] {
+					req.Method = method
+				}
+			}
+			next(w, req)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	r := newRouter()
+	r.POST("/items/1", text("post"))
+	r.PUT("/items/1", text("put"))
+	r.DELETE("/items/1", text("delete"))
+	r.GET("/items/1", text("get"))
+	h := MethodOverride()(r.ServeHTTP)
+
+	tests := []struct {
+		name     string
+		method   string
+		override string
+		form     string
+		body     string
+	}{
+		{"header", http.MethodPost, "DELETE", "", "delete"},
+		{"lower-case header", http.MethodPost, "put", "", "put"},
+		{"form field", http.MethodPost, "", "_method=DELETE", "delete"},
+		// 不在白名单中的方法被忽略
+		{"invalid override", http.MethodPost, "CONNECT", "", "post"},
+		{"GET cannot be overridden", http.MethodGet, "DELETE", "", "get"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/items/1", strings.NewReader(tt.form))
+		if tt.override != "" {
+			req.Header.Set("X-HTTP-Method-Override", tt.override)
+		}
+		if tt.form != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		w := httptest.NewRecorder()
+		h(w, req)
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.name, w.Body.String(), tt.body)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricBuckets 是请求耗时直方图的桶上界（秒），与 Prometheus 客户端的默认值一致
+var metricBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metricKey 结构体是一组请求指标的标签
+type metricKey struct {
+	method  string
+	pattern string // 匹配到的路由规则，而不是原始请求路径，避免标签数量随路径参数无限增长
+	status  int
+}
+
+// metricSeries 结构体记录同一组标签下的请求数和耗时分布
+type metricSeries struct {
+	count   uint64
+	sum     float64  // 耗时总和（秒）
+	buckets []uint64 // 耗时落在各个桶内的请求数（非累积），与 metricBuckets 一一对应
+}
+
+// metrics 结构体保存路由收集到的所有请求指标
+type metrics struct {
+	mu     sync.Mutex
+	series map[metricKey]*metricSeries
+}
+
+// newMetrics 函数用于创建一个空的指标集合
+func newMetrics() *metrics {
+	return &metrics{series: make(map[metricKey]*metricSeries)}
+}
+
+// observe 方法用于记录一次请求
+func (m *metrics) observe(key metricKey, latency time.Duration) {
+	seconds := latency.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[key]
+	if !ok {
+		s = &metricSeries{buckets: make([]uint64, len(metricBuckets))}
+		m.series[key] = s
+	}
+	s.count++
+	s.sum += seconds
+	if i := sort.SearchFloat64s(metricBuckets, seconds); i < len(metricBuckets) {
+		s.buckets[i]++
+	}
+}
+
+// Metrics 中间件用于按请求方法、匹配到的路由规则和状态码统计请求数和耗时，
+// 统计结果保存在处理请求的路由中，通过 MetricsHandler 以 Prometheus 文本格式导出
+func Metrics() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next(sw, req)
+
+			r, _ := req.Context().Value(routerKey).(*router)
+			if r == nil {
+				return
+			}
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			r.metrics.observe(metricKey{method: req.Method, pattern: MatchedPattern(req), status: status}, time.Since(start))
+		}
+	}
+}
+
+// MetricsHandler 方法用于返回导出请求指标的处理函数，输出 Prometheus 文本格式的
+// http_requests_total 计数器和 http_request_duration_seconds 直方图，例如 r.GET("/metrics", r.MetricsHandler())
+func (r *router) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(r.metrics.render()))
+	}
+}
+
+// render 方法用于按标签排序后生成 Prometheus 文本格式的指标
+func (m *metrics) render() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		if keys[i].pattern != keys[j].pattern {
+			return keys[i].pattern < keys[j].pattern
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	var b strings.Builder
+	b.WriteString("# HELP http_requests_total Total number of HTTP requests.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "http_requests_total{%s} %d\n", key.labels(), m.series[key].count)
+	}
+
+	b.WriteString("# HELP http_request_duration_seconds HTTP request latency in seconds.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for _, key := range keys {
+		s, labels := m.series[key], key.labels()
+		var cumulative uint64
+		for i, le := range metricBuckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{%s} %d\n", labels, s.count)
+	}
+	return b.String()
+}
+
+// labelEscaper 用于转义 Prometheus 标签值中的反斜杠、双引号和换行符
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels 方法用于生成指标的标签部分
+func (k metricKey) labels() string {
+	return fmt.Sprintf(`method="%s",route="%s",status="%d"`, labelEscaper.Replace(k.method), labelEscaper.Replace(k.pattern), k.status)
+}
+
+// RouteStats 结构体是单条路由的请求统计摘要
+type RouteStats struct {
+	Requests   uint64        // 请求总数
+	Errors     uint64        // 状态码不低于 500 的请求数
+	AvgLatency time.Duration // 平均耗时
+}
+
+// Stats 方法用于返回 Metrics 中间件收集到的按路由汇总的请求统计，键为 "方法-路由规则"，例如 "GET-/users/:id"。
+// 与 MetricsHandler 相比不区分状态码，适合快速诊断；没有匹配到路由的请求不计入其中
+func (r *router) Stats() map[string]RouteStats {
+	m := r.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sums := make(map[string]float64)
+	stats := make(map[string]RouteStats)
+	for key, s := range m.series {
+		if key.pattern == "" {
+			continue
+		}
+		name := key.method + "-" + key.pattern
+		st := stats[name]
+		st.Requests += s.count
+		if key.status >= http.StatusInternalServerError {
+			st.Errors += s.count
+		}
+		stats[name] = st
+		sums[name] += s.sum
+	}
+	for name, st := range stats {
+		st.AvgLatency = time.Duration(sums[name] / float64(st.Requests) * float64(time.Second))
+		stats[name] = st
+	}
+	return stats
+}
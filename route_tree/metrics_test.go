@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	r := newRouter()
+	r.Use(Metrics())
+	r.GET("/users/:id", text("user"))
+	r.GET("/metrics", r.MetricsHandler())
+
+	for _, target := range []string{"/users/1", "/users/2", "/users/3", "/missing"} {
+		serve(r, http.MethodGet, target)
+	}
+
+	out := serve(r, http.MethodGet, "/metrics").Body.String()
+	want := []string{
+		`http_requests_total{method="GET",route="/users/:id",status="200"} 3`,
+		`http_requests_total{method="GET",route="",status="404"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="200",le="10"} 3`,
+		`http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="200",le="+Inf"} 3`,
+		`http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 3`,
+	}
+	for _, line := range want {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("metrics output missing %q in\n%s", line, out)
+		}
+	}
+	// 按路由规则而不是具体路径统计
+	if strings.Contains(out, "/users/1") {
+		t.Errorf("metrics output contains a raw request path:\n%s", out)
+	}
+}
+
+func TestMetricsHostRoutes(t *testing.T) {
+	r := newRouter()
+	r.Use(Metrics())
+	r.Host("api.example.com").GET("/x", text("x"))
+	r.GET("/y", text("y"))
+
+	serveHost(r, "api.example.com", "/x")
+	serveHost(r, "www.example.com", "/y")
+
+	stats := r.Stats()
+	for _, key := range []string{"GET-/x", "GET-/y"} {
+		if stats[key].Requests != 1 {
+			t.Errorf("Stats()[%q].Requests = %d, want 1 (stats = %v)", key, stats[key].Requests, stats)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	r := newRouter()
+	r.Use(Metrics())
+	r.GET("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+	})
+	r.GET("/fail", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	for _, target := range []string{"/users/1", "/users/2", "/fail", "/fail", "/fail", "/missing"} {
+		serve(r, http.MethodGet, target)
+	}
+
+	stats := r.Stats()
+	if len(stats) != 2 {
+		t.Errorf("Stats() = %v, want two routes (unmatched requests are not counted)", stats)
+	}
+	users, fail := stats["GET-/users/:id"], stats["GET-/fail"]
+	if users.Requests != 2 || users.Errors != 0 {
+		t.Errorf("GET-/users/:id = %+v, want 2 requests, 0 errors", users)
+	}
+	if users.AvgLatency < 2*time.Millisecond {
+		t.Errorf("GET-/users/:id AvgLatency = %v, want at least 2ms", users.AvgLatency)
+	}
+	if fail.Requests != 3 || fail.Errors != 3 {
+		t.Errorf("GET-/fail = %+v, want 3 requests, 3 errors", fail)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware 表示一个中间件，它包装处理函数，可以在处理函数执行前后运行额外的逻辑
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Use 方法用于注册全局中间件，中间件按注册顺序由外到内包裹每一个匹配到的处理函数
+func (r *router) Use(mw ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewares = append(r.middlewares, mw...)
+}
+
+// chain 函数用于将中间件按顺序组合到处理函数外层，第一个中间件位于最外层。
+// 每个中间件和最终的处理函数执行前都会检查请求是否已经通过 Context.Abort 中止，中止后不再继续向内执行
+func chain(handler http.HandlerFunc, mws []Middleware) http.HandlerFunc {
+	handler = abortable(handler)
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = abortable(mws[i](handler))
+	}
+	return handler
+}
+
+// UseContext 方法用于注册以 *Context 为参数的全局中间件。中间件通过调用 c.Next() 执行链上剩余的部分，
+// Next 返回后还可以继续处理（例如统计耗时），不调用 Next 则后续的中间件和处理函数都不会执行。
+// 这类中间件按注册顺序执行，位于 Use 注册的中间件之内、处理函数之外
+func (r *router) UseContext(mw ...func(*Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctxMws = append(r.ctxMws, mw...)
+}
+
+// contextChain 函数用于将 UseContext 注册的中间件和处理函数组合为一个处理函数，
+// 执行顺序保存在每个请求的 Context 中，由 Context.Next 逐个推进
+func contextChain(handler http.HandlerFunc, mws []func(*Context)) http.HandlerFunc {
+	if len(mws) == 0 {
+		return handler
+	}
+	handlers := append(append(make([]func(*Context), 0, len(mws)+1), mws...), func(c *Context) {
+		handler(c.ResponseWriter, c.Req)
+	})
+	return func(w http.ResponseWriter, req *http.Request) {
+		c := newContext(w, req)
+		c.handlers = handlers
+		c.Next()
+	}
+}
+
+// abortable 函数用于包装处理函数，请求已中止时直接返回而不调用 next
+func abortable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if state, ok := req.Context().Value(stateKey).(*requestState); ok && state.aborted.Load() {
+			return
+		}
+		next(w, req)
+	}
+}
+
+// statusWriter 包装 http.ResponseWriter，记录响应状态码和已写入的字节数
+type statusWriter struct {
+	http.ResponseWriter
+	status int // 已写出的状态码，尚未写出时为 0
+	size   int // 已写出的响应体字节数
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += n
+	return n, err
+}
+
+// Unwrap 方法用于返回被包装的 http.ResponseWriter，使 http.ResponseController 能够找到 Flush、Hijack 等能力
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// written 方法用于判断响应头是否已经写出
+func (w *statusWriter) written() bool {
+	return w.status != 0
+}
+
+// Recovery 中间件用于捕获处理函数中的 panic，记录堆栈信息并返回 500，避免整个服务崩溃。
+// 如果 panic 发生前响应已经开始写出，则不再修改状态码
+func Recovery() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				if err := recover(); err != nil {
+					log.Printf("panic recovered: %v\n%s", err, debug.Stack())
+					if !sw.written() {
+						http.Error(sw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					}
+				}
+			}()
+			next(sw, req)
+		}
+	}
+}
+
+// MaxBodyBytes 中间件用于限制请求体的大小。声明的 Content-Length 超过 n 时直接返回 413，
+// 否则用 http.MaxBytesReader 包装请求体，处理函数（包括 BindJSON）读取超出部分时会得到错误。
+// 对于没有声明长度的分块上传，一旦读取超出限制，处理函数之后写出的响应都会被替换为 413
+func MaxBodyBytes(n int64) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.ContentLength > n {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, req.Body, n)}
+			req.Body = body
+			lw := &tooLargeWriter{ResponseWriter: w, body: body}
+			next(lw, req)
+			// 处理函数读取超限后没有写出任何响应时，同样返回 413
+			lw.reject()
+		}
+	}
+}
+
+// limitedBody 包装 http.MaxBytesReader 返回的请求体，记录读取是否超出了限制
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// tooLargeWriter 包装 http.ResponseWriter，请求体读取超出限制后，用 413 替换处理函数写出的响应
+type tooLargeWriter struct {
+	http.ResponseWriter
+	body     *limitedBody
+	written  bool // 处理函数是否在超限之前已经写出了响应头
+	rejected bool // 是否已经写出 413
+}
+
+// reject 方法用于在请求体超限且处理函数尚未写出响应时写出 413，返回处理函数的写入是否应被丢弃
+func (w *tooLargeWriter) reject() bool {
+	if w.written || !w.body.exceeded {
+		return false
+	}
+	if !w.rejected {
+		w.rejected = true
+		http.Error(w.ResponseWriter, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	}
+	return true
+}
+
+func (w *tooLargeWriter) WriteHeader(code int) {
+	if w.reject() {
+		return
+	}
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *tooLargeWriter) Write(p []byte) (int, error) {
+	if w.reject() {
+		return len(p), nil
+	}
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap 方法用于返回被包装的 http.ResponseWriter，使 http.ResponseController 能够找到 Flush、Hijack 等能力
+func (w *tooLargeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Logger 中间件用于记录访问日志，每个请求输出一行，包含方法、路径、状态码、响应大小和耗时，日志写入 log 包的默认 Logger
+func Logger() Middleware {
+	return LoggerWithLogger(log.Default())
+}
+
+// LoggerWithWriter 中间件与 Logger 相同，但将日志写入 out
+func LoggerWithWriter(out io.Writer) Middleware {
+	return LoggerWithLogger(log.New(out, "", log.LstdFlags))
+}
+
+// LoggerWithLogger 中间件与 Logger 相同，但使用指定的 *log.Logger 输出日志
+func LoggerWithLogger(l *log.Logger) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next(sw, req)
+
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			l.Printf("method=%s path=%q status=%d size=%d latency=%s",
+				req.Method, req.URL.Path, status, sw.size, time.Since(start))
+		}
+	}
+}
+
+// BasicAuth 中间件用于 HTTP 基本认证，凭据缺失、格式错误或 validate 返回 false 时响应 401 并设置 WWW-Authenticate 头。
+// validate 比较密码时应使用 subtle.ConstantTimeCompare 等常量时间比较，避免时序攻击
+func BasicAuth(validate func(user, pass string) bool, realm string) Middleware {
+	challenge := fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm)
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			user, pass, ok := req.BasicAuth()
+			if !ok || !validate(user, pass) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next(w, req)
+		}
+	}
+}
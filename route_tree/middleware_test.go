@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// record 函数用于创建一个把 name 追加到 calls 中的中间件，用于断言中间件的执行顺序
+func record(calls *[]string, name string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			*calls = append(*calls, name)
+			next(w, req)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	r := newRouter()
+	var calls []string
+	r.Use(record(&calls, "first"), record(&calls, "second"))
+	r.addRoute(http.MethodGet, "/", func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	serve(r, http.MethodGet, "/")
+	if want := []string{"first", "second", "handler"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
+
+// captureLog 函数用于在测试期间把 log 包默认 Logger 的输出重定向到返回的缓冲区
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	out, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func TestRecovery(t *testing.T) {
+	logs := captureLog(t)
+	r := newRouter()
+	r.Use(Recovery())
+	r.GET("/panic", func(w http.ResponseWriter, req *http.Request) { panic("boom") })
+	r.GET("/partial", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late boom")
+	})
+	r.GET("/ok", text("ok"))
+
+	if w := serve(r, http.MethodGet, "/panic"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking handler: status = %d, want 500", w.Code)
+	}
+	if !strings.Contains(logs.String(), "panic recovered: boom") || !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("log does not contain the panic and its stack trace:\n%s", logs)
+	}
+	// 响应已经开始写出时保留原有的状态码
+	if w := serve(r, http.MethodGet, "/partial"); w.Code != http.StatusAccepted {
+		t.Errorf("panic after WriteHeader: status = %d, want 202", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/ok"); w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("request after panic: status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
+
+// onlyReader 只暴露 Read 方法，使请求体的长度未知，模拟分块上传
+type onlyReader struct{ io.Reader }
+
+func TestMaxBodyBytes(t *testing.T) {
+	r := newRouter()
+	r.Use(MaxBodyBytes(16))
+	r.addRouteCtx(http.MethodPost, "/echo", func(c *Context) {
+		var payload struct {
+			Name string `json:"name"`
+		}
+		if err := c.BindJSON(&payload); err != nil {
+			c.String(http.StatusBadRequest, "bad request: %v", err)
+			return
+		}
+		c.String(http.StatusOK, "hello %s", payload.Name)
+	})
+
+	post := func(body io.Reader) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/echo", body))
+		return w
+	}
+
+	if w := post(strings.NewReader(`{"name":"bob"}`)); w.Code != http.StatusOK || w.Body.String() != "hello bob" {
+		t.Errorf("under the limit: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	large := `{"name":"` + strings.Repeat("x", 64) + `"}`
+	if w := post(strings.NewReader(large)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("declared length over the limit: status = %d, want 413", w.Code)
+	}
+	// 没有 Content-Length 时在读取请求体的过程中发现超限，处理函数返回的 400 被替换为 413
+	w := post(onlyReader{strings.NewReader(large)})
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunked body over the limit: status = %d, want 413", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "bad request") {
+		t.Errorf("chunked body over the limit: handler body leaked into the 413 response: %q", w.Body.String())
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r := newRouter()
+	r.Use(LoggerWithLogger(log.New(&buf, "", 0)))
+	r.GET("/hello", text("hello"))
+
+	tests := []struct {
+		target string
+		fields []string
+	}{
+		{"/hello", []string{"method=GET", `path="/hello"`, "status=200", "size=5", "latency="}},
+		{"/missing", []string{"method=GET", `path="/missing"`, "status=404", "latency="}},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		serve(r, http.MethodGet, tt.target)
+		line := buf.String()
+		if strings.Count(line, "\n") != 1 {
+			t.Fatalf("GET %s: logged %q, want a single line", tt.target, line)
+		}
+		for _, field := range tt.fields {
+			if !strings.Contains(line, field) {
+				t.Errorf("GET %s: log line %q missing %q", tt.target, line, field)
+			}
+		}
+	}
+}
+
+func TestLoggerWithWriter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newRouter()
+	r.Use(LoggerWithWriter(&buf))
+	r.POST("/items", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	serve(r, http.MethodPost, "/items")
+	if line := buf.String(); !strings.Contains(line, "method=POST") || !strings.Contains(line, "status=201") {
+		t.Errorf("log line = %q, want method=POST and status=201", line)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	r := newRouter()
+	r.Use(BasicAuth(func(user, pass string) bool {
+		return user == "admin" && pass == "secret"
+	}, "admin area"))
+	r.GET("/admin", text("welcome"))
+
+	tests := []struct {
+		name       string
+		user, pass string
+		setAuth    bool
+		code       int
+	}{
+		{"valid credentials", "admin", "secret", true, http.StatusOK},
+		{"missing header", "", "", false, http.StatusUnauthorized},
+		{"wrong password", "admin", "wrong", true, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		if tt.setAuth {
+			req.SetBasicAuth(tt.user, tt.pass)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.code)
+			continue
+		}
+		challenge := w.Header().Get("WWW-Authenticate")
+		if tt.code == http.StatusUnauthorized && challenge != `Basic realm="admin area", charset="UTF-8"` {
+			t.Errorf("%s: WWW-Authenticate = %q", tt.name, challenge)
+		}
+		if tt.code == http.StatusOK && w.Body.String() != "welcome" {
+			t.Errorf("%s: body = %q", tt.name, w.Body.String())
+		}
+	}
+}
+
+func TestAbort(t *testing.T) {
+	r := newRouter()
+	var calls []string
+	r.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, "auth")
+			if req.Header.Get("Authorization") == "" {
+				c := newContext(w, req)
+				c.String(http.StatusUnauthorized, "unauthorized")
+				c.Abort()
+			}
+			// 中止后仍调用 next，链上后续的中间件和处理函数也不会执行
+			next(w, req)
+		}
+	})
+	r.Use(record(&calls, "logger"))
+	r.GET("/secret", func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler")
+		w.Write([]byte("secret"))
+	})
+
+	w := serve(r, http.MethodGet, "/secret")
+	if w.Code != http.StatusUnauthorized || w.Body.String() != "unauthorized" {
+		t.Errorf("aborted: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if want := []string{"auth"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("aborted: calls = %v, want %v", calls, want)
+	}
+
+	calls = nil
+	req := httptest.NewRequest(http.MethodGet, "/secret", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "secret" {
+		t.Errorf("authorized: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if want := []string{"auth", "logger", "handler"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("authorized: calls = %v, want %v", calls, want)
+	}
+}
+
+func TestUseContextNext(t *testing.T) {
+	r := newRouter()
+	var calls []string
+	step := func(name string) func(*Context) {
+		return func(c *Context) {
+			calls = append(calls, name+" before")
+			c.Next()
+			calls = append(calls, name+" after")
+		}
+	}
+	r.UseContext(step("outer"), step("inner"))
+	r.UseContext(func(c *Context) {
+		// 不调用 Next 时链在这里结束，处理函数不会执行
+		if c.Query("stop") != "" {
+			calls = append(calls, "stop")
+			c.String(http.StatusForbidden, "stopped")
+			return
+		}
+		c.Next()
+	})
+	r.GET("/", func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	serve(r, http.MethodGet, "/")
+	want := []string{"outer before", "inner before", "handler", "inner after", "outer after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	calls = nil
+	w := serve(r, http.MethodGet, "/?stop=1")
+	want = []string{"outer before", "inner before", "stop", "inner after", "outer after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("without Next: calls = %v, want %v", calls, want)
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("without Next: status = %d, want 403", w.Code)
+	}
+}
+
+func TestFallbacksPassThroughMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	r := newRouter()
+	r.Use(LoggerWithLogger(log.New(&buf, "", 0)))
+	r.GET("/items", text("list"))
+	r.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "custom 405", http.StatusMethodNotAllowed)
+	}))
+
+	tests := []struct {
+		method, path, status string
+	}{
+		{http.MethodGet, "/missing", "status=404"},
+		{http.MethodDelete, "/items", "status=405"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		serve(r, tt.method, tt.path)
+		if !strings.Contains(buf.String(), tt.status) {
+			t.Errorf("%s %s: log line %q missing %q", tt.method, tt.path, buf.String(), tt.status)
+		}
+	}
+}
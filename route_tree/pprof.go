@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// EnablePprof 方法用于在 prefix 前缀下注册 net/http/pprof 提供的性能分析接口，
+// 例如 r.EnablePprof("/debug/pprof") 后可以访问 /debug/pprof/、/debug/pprof/heap 和 /debug/pprof/profile。
+// 这些接口会暴露运行时信息，生产环境中应配合 BasicAuth 等中间件或只在内网开启
+func (r *router) EnablePprof(prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	r.GET(prefix+"/", pprof.Index)
+	r.GET(prefix+"/cmdline", pprof.Cmdline)
+	r.GET(prefix+"/profile", pprof.Profile)
+	r.GET(prefix+"/symbol", pprof.Symbol)
+	r.POST(prefix+"/symbol", pprof.Symbol)
+	r.GET(prefix+"/trace", pprof.Trace)
+
+	// pprof.Index 只能识别 /debug/pprof/ 下的 profile 名称，
+	// 因此 heap、goroutine 等其余 profile 由通配符路由取出名称后交给 pprof.Handler
+	r.GET(prefix+"/*name", func(w http.ResponseWriter, req *http.Request) {
+		params := req.Context().Value(paramsKey).(map[string]string)
+		pprof.Handler(strings.TrimPrefix(params["name"], "/")).ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEnablePprof(t *testing.T) {
+	r := newRouter()
+	r.EnablePprof("/debug/pprof")
+
+	w := serve(r, http.MethodGet, "/debug/pprof/cmdline")
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Fatalf("cmdline: status = %d, body length = %d", w.Code, w.Body.Len())
+	}
+
+	// 其余 profile 经过通配符路由交给 pprof.Handler
+	w = serve(r, http.MethodGet, "/debug/pprof/goroutine?debug=1")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine profile") {
+		t.Errorf("goroutine: status = %d, body = %.80q", w.Code, w.Body.String())
+	}
+
+	w = serve(r, http.MethodGet, "/debug/pprof/")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "heap") {
+		t.Errorf("index: status = %d, body = %.80q", w.Code, w.Body.String())
+	}
+}
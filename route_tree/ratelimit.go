@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLimiterIdle 是限流器默认的空闲清理时间，超过该时间没有请求的客户端状态会被删除
+const defaultLimiterIdle = 10 * time.Minute
+
+// RateLimitOptions 结构体用于配置限流中间件
+type RateLimitOptions struct {
+	TrustForwardedFor bool          // 是否采信 X-Forwarded-For，规则见 limiterKey，只应在可信代理之后开启
+	IdleTimeout       time.Duration // 客户端空闲多久后清理其令牌桶，为 0 时使用 10 分钟
+}
+
+// RateLimit 中间件用于按客户端 IP 限流，每个 IP 拥有一个令牌桶，每秒补充 rps 个令牌，最多积累 burst 个。
+// 令牌耗尽时返回 429 并设置 Retry-After 头
+func RateLimit(rps float64, burst int) Middleware {
+	return RateLimitWithOptions(rps, burst, RateLimitOptions{})
+}
+
+// RateLimitWithOptions 中间件与 RateLimit 相同，但可以通过 opts 调整客户端 IP 的获取方式和空闲清理时间。
+// rps 不是正数或 burst 小于 1 时 panic，这样的配置会让所有请求都被拒绝，Retry-After 也无法计算
+func RateLimitWithOptions(rps float64, burst int, opts RateLimitOptions) Middleware {
+	if !(rps > 0) {
+		panic(fmt.Sprintf("rate limit: rps must be positive, got %v", rps))
+	}
+	if burst < 1 {
+		panic(fmt.Sprintf("rate limit: burst must be at least 1, got %d", burst))
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = defaultLimiterIdle
+	}
+	l := &rateLimiter{
+		rps:         rps,
+		burst:       float64(burst),
+		idle:        opts.IdleTimeout,
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			ok, retryAfter := l.allow(limiterKey(w, req, opts.TrustForwardedFor))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next(w, req)
+		}
+	}
+}
+
+// limiterKey 函数用于获取限流使用的客户端 IP。trustForwardedFor 为 false 时使用 RemoteAddr 中的地址；
+// 为 true 且路由通过 SetTrustedProxies 设置了可信代理时，使用 Context.ClientIP 的规则；
+// 没有设置可信代理时使用 X-Forwarded-For 中最右边的地址，它由直接相连的代理写入，
+// 客户端自己伪造的地址只会出现在它的左边
+func limiterKey(w http.ResponseWriter, req *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if c := newContext(w, req); c.router != nil && c.router.hasTrustedProxies() {
+			return c.ClientIP()
+		}
+		if values := req.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			last := values[len(values)-1]
+			if i := strings.LastIndexByte(last, ','); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := net.ParseIP(strings.TrimSpace(last)); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// tokenBucket 记录一个客户端的令牌数量和上次补充令牌的时间
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter 保存所有客户端的令牌桶
+type rateLimiter struct {
+	mu          sync.Mutex
+	rps         float64
+	burst       float64
+	idle        time.Duration
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+// allow 方法用于为 key 消耗一个令牌，令牌不足时返回 false 和建议的重试等待秒数
+func (l *rateLimiter) allow(key string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.cleanup(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rps)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, int(math.Ceil((1 - b.tokens) / l.rps))
+}
+
+// cleanup 方法用于定期删除空闲超过 idle 的令牌桶，限制内存占用，调用方需要持有锁
+func (l *rateLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < l.idle {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
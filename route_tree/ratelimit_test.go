@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serveFrom 函数用于模拟来自 remoteAddr 的请求，xff 非空时设置 X-Forwarded-For 头
+func serveFrom(r *router, remoteAddr, xff string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.RemoteAddr = remoteAddr
+	if xff != "" {
+		req.Header.Set("X-Forwarded-For", xff)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	r := newRouter()
+	r.Use(RateLimit(10, 2))
+	r.GET("/api", text("ok"))
+
+	codes := make([]int, 0, 4)
+	for i := 0; i < 4; i++ {
+		codes = append(codes, serveFrom(r, "198.51.100.1:1000", "").Code)
+	}
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("burst: statuses = %v, want %v", codes, want)
+		}
+	}
+	if w := serveFrom(r, "198.51.100.1:1000", ""); w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", w.Header().Get("Retry-After"))
+	}
+
+	// 其他客户端拥有独立的令牌桶
+	if w := serveFrom(r, "198.51.100.2:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", w.Code)
+	}
+
+	// 每秒补充 10 个令牌，等待 150ms 后至少有一个令牌
+	time.Sleep(150 * time.Millisecond)
+	if w := serveFrom(r, "198.51.100.1:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("after refill: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	r := newRouter()
+	r.Use(RateLimitWithOptions(1, 1, RateLimitOptions{TrustForwardedFor: true}))
+	r.GET("/api", text("ok"))
+
+	// 客户端可以随意改写最左边的地址，限流以代理追加的最右边地址为准
+	if w := serveFrom(r, "10.0.0.1:1000", "1.1.1.1, 203.0.113.7"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", w.Code)
+	}
+	if w := serveFrom(r, "10.0.0.1:1000", "2.2.2.2, 203.0.113.7"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed leftmost entry: status = %d, want 429", w.Code)
+	}
+	if w := serveFrom(r, "10.0.0.1:1000", "203.0.113.8"); w.Code != http.StatusOK {
+		t.Errorf("other client behind the proxy: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitTrustedProxies(t *testing.T) {
+	r := newRouter()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(RateLimitWithOptions(1, 1, RateLimitOptions{TrustForwardedFor: true}))
+	r.GET("/api", text("ok"))
+
+	// 经过两层可信代理，客户端地址是跳过可信代理后最右边的地址
+	if w := serveFrom(r, "10.0.0.1:1000", "6.6.6.6, 203.0.113.7, 10.0.0.2"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", w.Code)
+	}
+	if w := serveFrom(r, "10.0.0.1:1000", "7.7.7.7, 203.0.113.7, 10.0.0.2"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed leftmost entry: status = %d, want 429", w.Code)
+	}
+	// 不是来自可信代理的请求不采信 X-Forwarded-For
+	if w := serveFrom(r, "192.0.2.9:1000", "203.0.113.7"); w.Code != http.StatusOK {
+		t.Errorf("untrusted peer: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitInvalidConfig(t *testing.T) {
+	tests := []struct {
+		rps   float64
+		burst int
+		want  string
+	}{
+		{0, 1, "rate limit: rps must be positive, got 0"},
+		{-2, 1, "rate limit: rps must be positive, got -2"},
+		{1, 0, "rate limit: burst must be at least 1, got 0"},
+	}
+	for _, tt := range tests {
+		if msg := panicMessage(func() { RateLimit(tt.rps, tt.burst) }); msg != tt.want {
+			t.Errorf("RateLimit(%v, %d): panic = %q, want %q", tt.rps, tt.burst, msg, tt.want)
+		}
+	}
+}
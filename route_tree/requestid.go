@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// requestIDHeader 是传递请求 ID 使用的请求头和响应头
+const requestIDHeader = "X-Request-ID"
+
+// RequestID 中间件用于为每个请求分配请求 ID：请求头中已有 X-Request-ID 时沿用，否则生成一个 UUID 形式的随机 ID。
+// 请求 ID 会写入响应头，并存入请求上下文，通过 RequestIDFromContext 读取，便于关联日志
+func RequestID() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(requestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(requestIDHeader, id)
+			next(w, req.WithContext(context.WithValue(req.Context(), requestIDKey, id)))
+		}
+	}
+}
+
+// RequestIDFromContext 函数用于从上下文中读取 RequestID 中间件设置的请求 ID，没有时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// newRequestID 函数用于生成一个随机的版本 4 UUID
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
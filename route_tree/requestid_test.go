@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	r := newRouter()
+	r.Use(RequestID())
+	var seen string
+	r.GET("/", func(w http.ResponseWriter, req *http.Request) {
+		seen = RequestIDFromContext(req.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if seen != "abc-123" || w.Header().Get("X-Request-ID") != "abc-123" {
+		t.Errorf("incoming ID: context = %q, header = %q, want abc-123", seen, w.Header().Get("X-Request-ID"))
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ids := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		w := serve(r, http.MethodGet, "/")
+		id := w.Header().Get("X-Request-ID")
+		if !uuid.MatchString(id) {
+			t.Fatalf("generated ID %q is not a version 4 UUID", id)
+		}
+		if seen != id {
+			t.Fatalf("context ID = %q, response header = %q", seen, id)
+		}
+		if ids[id] {
+			t.Fatalf("generated ID %q twice", id)
+		}
+		ids[id] = true
+	}
+}
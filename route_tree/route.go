@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Route 结构体描述一条路由。GET、POST 等注册方法返回已注册的 Route，可以继续设置参数约束等选项；
+// 也可以直接构造 Route 列表，通过 Register 以声明的方式一次注册整个路由表
+type Route struct {
+	Method  string           // HTTP 方法
+	Pattern string           // 路由规则
+	Handler http.HandlerFunc // 处理函数
+	Name    string           // 路由名称，可选，用于 URL 反向生成路径
+
+	router *router // 注册该路由的路由，直接构造且尚未注册的 Route 中为 nil
+}
+
+// Register 方法用于批量注册 routes 中的路由。每条路由单独校验和注册，某条路由无效或冲突时不影响其他路由，
+// 所有失败的路由的错误会合并后返回，全部成功时返回 nil
+func (r *router) Register(routes []Route) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for i, route := range routes {
+		if route.Handler == nil {
+			errs = append(errs, fmt.Errorf("route %d (%s %s): nil handler", i, route.Method, route.Pattern))
+			continue
+		}
+		if _, err := r.insertRouteE(route.Method, route.Pattern, route.Handler); err != nil {
+			errs = append(errs, fmt.Errorf("route %d: %w", i, err))
+			continue
+		}
+		if route.Name != "" {
+			r.names[route.Name] = RouteInfo{Method: strings.ToUpper(route.Method), Pattern: route.Pattern, Name: route.Name}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registered 方法用于返回注册该路由的路由，直接构造且尚未注册的 Route 会 panic
+func (rt *Route) registered() *router {
+	if rt.router == nil {
+		panic(fmt.Sprintf("route %s %s is not registered", rt.Method, rt.Pattern))
+	}
+	return rt.router
+}
+
+// ParamConstraint 表示对单个路由参数值的约束，返回 false 时该路由视为不匹配
+type ParamConstraint func(value string) bool
+
+// 常用的参数字符集约束
+var (
+	ParamAlpha    = charsetConstraint(func(c rune) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' })
+	ParamNumeric  = charsetConstraint(func(c rune) bool { return c >= '0' && c <= '9' })
+	ParamAlphaNum = charsetConstraint(func(c rune) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' })
+	ParamHex      = charsetConstraint(func(c rune) bool { return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' })
+)
+
+// charsetConstraint 函数用于创建要求参数值非空且每个字符都满足 allowed 的约束
+func charsetConstraint(allowed func(rune) bool) ParamConstraint {
+	return func(value string) bool {
+		return value != "" && strings.IndexFunc(value, func(c rune) bool { return !allowed(c) }) < 0
+	}
+}
+
+// ParamMaxLen 函数用于创建限制参数值最多 n 个字符的约束
+func ParamMaxLen(n int) ParamConstraint {
+	return func(value string) bool {
+		return utf8.RuneCountInString(value) <= n
+	}
+}
+
+// Where 方法用于为路由参数 name 设置约束，例如 r.GET("/u/:id", h).Where("id", ParamHex, ParamMaxLen(32))。
+// 与正则约束一样，参数值不满足约束时该路由视为不匹配，请求会继续尝试其他路由，都不匹配时返回 404。
+// 路由规则中没有名为 name 的参数时 panic
+func (rt *Route) Where(name string, constraints ...ParamConstraint) *Route {
+	r := rt.registered()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := paramIndex(rt.Pattern, name); !ok {
+		panic(fmt.Sprintf("route '%s' has no param '%s'", rt.Pattern, name))
+	}
+	n := r.routeNode(rt.Method, rt.Pattern)
+	if n == nil {
+		panic(fmt.Sprintf("route not found: %s %s", rt.Method, rt.Pattern))
+	}
+	if n.where == nil {
+		n.where = make(map[string][]ParamConstraint)
+	}
+	n.where[name] = append(n.where[name], constraints...)
+	return rt
+}
+
+// copyWhere 函数用于复制节点上的参数约束，Where 会原地追加约束，挂载到其他路由时不能共享同一个 map
+func copyWhere(where map[string][]ParamConstraint) map[string][]ParamConstraint {
+	if where == nil {
+		return nil
+	}
+	cp := make(map[string][]ParamConstraint, len(where))
+	for name, constraints := range where {
+		cp[name] = append([]ParamConstraint(nil), constraints...)
+	}
+	return cp
+}
+
+// WithMeta 方法用于为路由附加元数据，例如 r.GET("/admin", h).WithMeta("requiresRole", "admin")，
+// 中间件可以通过 RouteMeta 读取匹配到的路由的元数据，而不必按路径硬编码判断
+func (rt *Route) WithMeta(key string, value interface{}) *Route {
+	r := rt.registered()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.routeNode(rt.Method, rt.Pattern)
+	if n == nil {
+		panic(fmt.Sprintf("route not found: %s %s", rt.Method, rt.Pattern))
+	}
+	// 处理中的请求可能持有旧的 map，因此复制后整体替换，而不是原地修改
+	meta := make(map[string]interface{}, len(n.meta)+1)
+	for k, v := range n.meta {
+		meta[k] = v
+	}
+	meta[key] = value
+	n.meta = meta
+	return rt
+}
+
+// WithTimeout 方法用于为路由单独设置请求 context 的超时时间，覆盖 SetDefaultTimeout 设置的默认值，d 为 0 时不设置超时。
+// 超时时间保存在路由节点上，不会出现在 RouteMeta 读取的元数据中
+func (rt *Route) WithTimeout(d time.Duration) *Route {
+	r := rt.registered()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.routeNode(rt.Method, rt.Pattern)
+	if n == nil {
+		panic(fmt.Sprintf("route not found: %s %s", rt.Method, rt.Pattern))
+	}
+	n.timeout = &d
+	return rt
+}
+
+// RouteMeta 函数用于读取处理当前请求的路由上通过 WithMeta 附加的元数据，没有匹配到路由或键不存在时第二个返回值为 false
+func RouteMeta(req *http.Request, key string) (interface{}, bool) {
+	meta, _ := req.Context().Value(metaKey).(map[string]interface{})
+	value, ok := meta[key]
+	return value, ok
+}
+
+// routeNode 方法用于查找路由规则 pattern 对应的路由树节点，调用方需要持有锁
+func (r *router) routeNode(method, pattern string) *node {
+	root, ok := r.roots[strings.ToUpper(method)]
+	if !ok {
+		return nil
+	}
+	var found *node
+	root.walk(func(n *node) {
+		if n.pattern == pattern {
+			found = n
+		}
+	})
+	return found
+}
+
+// paramIndex 函数用于返回参数 name 所在路径段的下标，复合参数所在的路径段也会被找到
+func paramIndex(pattern, name string) (int, bool) {
+	for i, part := range parsePattern(pattern) {
+		switch {
+		case isCompositePart(part):
+			names, _ := parseComposite(part)
+			for _, n := range names {
+				if n == name {
+					return i, true
+				}
+			}
+		case part[0] == ':':
+			if n, _ := splitParam(part); n == name {
+				return i, true
+			}
+		case part[0] == '*':
+			if part[1:] == name {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// satisfies 方法用于判断 parts 中的参数值是否满足该节点上的 Where 约束，没有约束时直接返回 true。
+// 通配符的值按 catchAllSlash 拼接，与处理函数收到的值完全相同
+func (n *node) satisfies(parts []string, catchAllSlash bool) bool {
+	if len(n.where) == 0 {
+		return true
+	}
+	for i, part := range parsePattern(n.pattern) {
+		// 缺省的可选参数不检查约束
+		if i >= len(parts) {
+			break
+		}
+		if !isWildPart(part) {
+			continue
+		}
+		values := make(map[string]string)
+		switch {
+		case isCompositePart(part):
+			names, _ := parseComposite(part)
+			if m := compositeRegexp(part).FindStringSubmatch(parts[i]); m != nil {
+				for j, name := range names {
+					values[name] = m[j+1]
+				}
+			}
+		case part[0] == ':':
+			name, _ := splitParam(part)
+			values[name] = parts[i]
+		default:
+			values[part[1:]] = catchAllValue(parts[i:], catchAllSlash)
+		}
+		for name, value := range values {
+			for _, ok := range n.where[name] {
+				if !ok(value) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
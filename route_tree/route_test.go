@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWhereConstraints(t *testing.T) {
+	r := newRouter()
+	r.GET("/u/:id", text("hex user")).Where("id", ParamHex, ParamMaxLen(8))
+	r.GET("/u/:id/posts", text("posts"))
+	r.GET("/v/:name", text("named")).Where("name", ParamAlpha)
+	r.GET("/v/:name/x", text("x"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/u/deadBEEF", http.StatusOK, "hex user"},
+		// 不满足约束时路由视为不匹配
+		{"/u/xyz", http.StatusNotFound, ""},
+		{"/u/0123456789", http.StatusNotFound, ""},
+		{"/v/gopher", http.StatusOK, "named"},
+		{"/v/gopher42", http.StatusNotFound, ""},
+		// 约束只作用于设置了约束的路由
+		{"/u/xyz/posts", http.StatusOK, "posts"},
+		{"/v/42/x", http.StatusOK, "x"},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s: status = %d, body = %q, want %d, %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+
+	// 约束不通过时继续尝试其他路由
+	r.GET("/f/:id", text("file id")).Where("id", ParamNumeric)
+	r.GET("/f/*rest", text("file rest"))
+	for path, want := range map[string]string{"/f/42": "file id", "/f/readme": "file rest"} {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != want {
+			t.Errorf("GET %s: status = %d, body = %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+
+	if msg := panicMessage(func() { r.GET("/z/:id", text("z")).Where("name", ParamHex) }); msg != "route '/z/:id' has no param 'name'" {
+		t.Errorf("Where on an unknown param: panic = %q", msg)
+	}
+}
+
+func TestWhereConstraintsAfterMount(t *testing.T) {
+	sub := newRouter()
+	route := sub.GET("/u/:id", text("user")).Where("id", ParamNumeric)
+	r := newRouter()
+	r.Mount("/admin", sub)
+
+	// 挂载后的路由与子路由本身的匹配结果一致
+	for _, tt := range []struct {
+		path string
+		code int
+	}{
+		{"/u/42", http.StatusOK},
+		{"/u/abc", http.StatusNotFound},
+	} {
+		if w := serve(sub, http.MethodGet, tt.path); w.Code != tt.code {
+			t.Errorf("sub: GET %s: status = %d, want %d", tt.path, w.Code, tt.code)
+		}
+		if w := serve(r, http.MethodGet, "/admin"+tt.path); w.Code != tt.code {
+			t.Errorf("GET /admin%s: status = %d, want %d", tt.path, w.Code, tt.code)
+		}
+	}
+
+	// 挂载时复制约束，之后在子路由上追加的约束不影响已挂载的路由
+	route.Where("id", ParamMaxLen(1))
+	if w := serve(r, http.MethodGet, "/admin/u/42"); w.Code != http.StatusOK {
+		t.Errorf("GET /admin/u/42 after Where on the sub-router: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	r := newRouter()
+	// 鉴权中间件从匹配到的路由的元数据中读取所需角色，而不是按路径判断
+	r.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if role, ok := RouteMeta(req, "requiresRole"); ok && req.Header.Get("X-Role") != role {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next(w, req)
+		}
+	})
+	r.GET("/admin", text("admin")).WithMeta("requiresRole", "admin")
+	r.GET("/public", text("public"))
+
+	tests := []struct {
+		path, role string
+		code       int
+	}{
+		{"/admin", "", http.StatusForbidden},
+		{"/admin", "user", http.StatusForbidden},
+		{"/admin", "admin", http.StatusOK},
+		{"/public", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("X-Role", tt.role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("GET %s as %q: status = %d, want %d", tt.path, tt.role, w.Code, tt.code)
+		}
+	}
+}
+
+func TestRouteMetaAfterMount(t *testing.T) {
+	sub := newRouter()
+	sub.GET("/users", func(w http.ResponseWriter, req *http.Request) {
+		role, ok := RouteMeta(req, "requiresRole")
+		fmt.Fprintf(w, "%v %v", role, ok)
+	}).WithMeta("requiresRole", "admin")
+	sub.GET("/report", func(w http.ResponseWriter, req *http.Request) {
+		_, ok := req.Context().Deadline()
+		fmt.Fprint(w, ok)
+	}).WithTimeout(time.Minute)
+	r := newRouter()
+	r.Mount("/admin", sub)
+
+	if w := serve(r, http.MethodGet, "/admin/users"); w.Body.String() != "admin true" {
+		t.Errorf("GET /admin/users: RouteMeta = %q, want %q", w.Body.String(), "admin true")
+	}
+	// 子路由上设置的超时时间在挂载后仍然生效
+	if w := serve(r, http.MethodGet, "/admin/report"); w.Body.String() != "true" {
+		t.Errorf("GET /admin/report: context has no deadline")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := newRouter()
+	err := r.Register([]Route{
+		{Method: http.MethodGet, Pattern: "/users", Handler: text("users"), Name: "users"},
+		{Method: http.MethodGet, Pattern: "/users/:id", Handler: text("user")},
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if w := serve(r, http.MethodGet, "/users/1"); w.Body.String() != "user" {
+		t.Errorf("GET /users/1: body = %q", w.Body.String())
+	}
+	if url, err := r.URL("users", nil); err != nil || url != "/users" {
+		t.Errorf(`URL("users") = %q, %v`, url, err)
+	}
+
+	// 冲突的路由单独报错，同一批中的其他路由照常注册
+	err = r.Register([]Route{
+		{Method: http.MethodGet, Pattern: "/posts", Handler: text("posts")},
+		{Method: http.MethodGet, Pattern: "/users/:name", Handler: text("conflict")},
+		{Method: http.MethodGet, Pattern: "/tags", Handler: nil},
+		{Method: http.MethodGet, Pattern: "/about", Handler: text("about")},
+	})
+	want := "route 1: wildcard ':name' in route '/users/:name' conflicts with wildcard ':id' in existing route '/users/:id'\n" +
+		"route 2 (GET /tags): nil handler"
+	if err == nil || err.Error() != want {
+		t.Fatalf("Register error = %v, want %q", err, want)
+	}
+	for path, body := range map[string]string{"/posts": "posts", "/about": "about", "/users/1": "user"} {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != body {
+			t.Errorf("GET %s: body = %q, want %q", path, w.Body.String(), body)
+		}
+	}
+	if w := serve(r, http.MethodGet, "/tags"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /tags: status = %d, want 404", w.Code)
+	}
+}
@@ -1,30 +1,242 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"html/template"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// node 结构体标识路由树的节点
+// node 结构体标识路由树的节点。
+// 只有一个静态子节点的静态节点链会被压缩成一个节点，此时 part 由多段路径组成，例如 "api/v1/users"
 type node struct {
-	pattern  string  // 路由规则
-	part     string  // 路由规则中的一个部分
-	children []*node // 子节点
-	isWild   bool    // 是否为通配符
+	pattern  string   // 路由规则
+	part     string   // 路由规则中的一个部分
+	segs     []string // 静态节点包含的路径段，即 part 按 / 切分的结果，通配符节点为空
+	children []*node  // 子节点
+	isWild   bool     // 是否为通配符
+
+	re *regexp.Regexp // 参数的正则约束，例如 :id(\d+)，没有约束时为 nil
+
+	where map[string][]ParamConstraint // 通过 Route.Where 为该节点上的路由规则设置的参数约束
+	meta  map[string]interface{}       // 通过 Route.WithMeta 为该节点上的路由规则附加的元数据，修改时整体替换
+
+	timeout *time.Duration // 通过 Route.WithTimeout 为该节点上的路由规则设置的超时时间，没有设置时为 nil
+}
+
+// match 方法用于判断当前节点能否匹配 parts 从 height 开始的部分，返回匹配的段数，不能匹配时返回 0。
+// 静态节点与 parts 比较，参数的正则约束则作用于 values 中的原始值：开启 CaseInsensitive 时 parts 是转换为小写后的路径段
+func (n *node) match(parts, values []string, height int) int {
+	if n.isWild {
+		// 尾部斜杠标记不是真实的路径段，不能作为参数的值
+		if parts[height] == slashMarker {
+			return 0
+		}
+		if n.re == nil || n.re.MatchString(values[height]) {
+			return 1
+		}
+		return 0
+	}
+	if len(parts)-height < len(n.segs) {
+		return 0
+	}
+	for i, seg := range n.segs {
+		if parts[height+i] != seg {
+			return 0
+		}
+	}
+	return len(n.segs)
+}
+
+// isWildPart 函数用于判断路由规则中的一个部分是否为参数或通配符
+func isWildPart(part string) bool {
+	return part[0] == ':' || part[0] == '*'
+}
+
+// literalReplacer 用于还原路由规则静态部分中被转义的 : 和 *
+var literalReplacer = strings.NewReplacer(`\:`, ":", `\*`, "*")
+
+// escapeStripper 用于在校验路由规则时去掉转义过的 : 和 *
+var escapeStripper = strings.NewReplacer(`\:`, "", `\*`, "")
+
+// literal 函数用于将路由规则中的静态部分转换为实际匹配时使用的字面值，例如 \:section 转换为 :section。
+// 转义后的部分以 \ 而不是 : 或 * 开头，因此不会被 isWildPart 当作参数或通配符
+func literal(part string) string {
+	if strings.IndexByte(part, '\\') < 0 {
+		return part
+	}
+	return literalReplacer.Replace(part)
+}
+
+// splitParam 函数用于将形如 :id(\d+) 的参数拆分为参数名 id 和正则约束 \d+，没有约束时 expr 为空。
+// 可选参数末尾的 ? 不属于参数名
+func splitParam(part string) (name, expr string) {
+	name = strings.TrimSuffix(part[1:], "?")
+	if i := strings.IndexByte(name, '('); i > 0 && strings.HasSuffix(name, ")") {
+		return name[:i], name[i+1 : len(name)-1]
+	}
+	return name, ""
+}
+
+// isOptionalPart 函数用于判断路由规则中的一个部分是否为可选参数，例如 :month?，可选参数只能是最后一段
+func isOptionalPart(part string) bool {
+	return part[0] == ':' && strings.HasSuffix(part, "?") && !isCompositePart(part)
+}
+
+// isCompositePart 函数用于判断参数部分是否在一个路径段中包含多个参数，例如 :name.:ext
+func isCompositePart(part string) bool {
+	return part[0] == ':' && strings.Contains(part[1:], ":")
+}
+
+// parseComposite 函数用于将 :name.:ext 这样的复合参数拆分为参数名 [name ext]，
+// 以及紧跟在每个参数后面的字面分隔符 [. ""]
+func parseComposite(part string) (names, seps []string) {
+	for _, token := range strings.Split(part[1:], ":") {
+		i := 0
+		for i < len(token) && (token[i] == '_' || unicode.IsLetter(rune(token[i])) || unicode.IsDigit(rune(token[i]))) {
+			i++
+		}
+		names = append(names, token[:i])
+		seps = append(seps, token[i:])
+	}
+	return names, seps
+}
+
+// compositeCache 用于缓存复合参数编译后的正则表达式，键为路由规则中的部分
+var compositeCache sync.Map
+
+// compositeRegexp 函数用于将复合参数编译为带捕获组的正则表达式，每个参数对应一个捕获组。
+// 除最后一个参数外都采用非贪婪匹配，例如 :name.:ext 匹配 archive.tar.gz 时 name=archive，ext=tar.gz
+func compositeRegexp(part string) *regexp.Regexp {
+	if re, ok := compositeCache.Load(part); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	names, seps := parseComposite(part)
+	var b strings.Builder
+	b.WriteString("^")
+	for i := range names {
+		if i == len(names)-1 && seps[i] == "" {
+			b.WriteString("(.+)")
+		} else {
+			b.WriteString("(.+?)")
+		}
+		b.WriteString(regexp.QuoteMeta(seps[i]))
+	}
+	b.WriteString("$")
+
+	re := regexp.MustCompile(b.String())
+	compositeCache.Store(part, re)
+	return re
+}
+
+// partRegexp 函数用于返回参数部分的匹配约束：复合参数对应的正则或 :id(\d+) 中的正则约束，没有约束时返回 nil
+func partRegexp(part string) *regexp.Regexp {
+	if part[0] != ':' {
+		return nil
+	}
+	if isCompositePart(part) {
+		return compositeRegexp(part)
+	}
+	if _, expr := splitParam(part); expr != "" {
+		return regexp.MustCompile("^(?:" + expr + ")$")
+	}
+	return nil
+}
+
+// newChild 方法用于为通配符部分 part 创建子节点
+func (n *node) newChild(part string) *node {
+	child := &node{part: part, isWild: isWildPart(part), re: partRegexp(part)}
+	n.addChild(child)
+	return child
 }
 
-// matchChild 方法用于在子节点中查找指定 part 匹配的节点
-func (n *node) matchChild(part string) *node {
+// addChild 方法用于添加子节点，并保持子节点按匹配优先级排序：静态节点在前，按首个路径段排序；
+// 通配符节点在后，按 wildRank 排序，即带正则约束的参数节点（包括复合参数）、无约束的参数节点、通配符 * 节点，
+// 同一优先级内按 part 排序。这样匹配结果只取决于注册了哪些路由，与注册顺序无关，
+// 并且只有静态和参数分支都无法匹配时，才会退回到通配符 * 节点
+func (n *node) addChild(child *node) {
+	n.children = append(n.children, child)
+	sort.SliceStable(n.children, func(i, j int) bool {
+		a, b := n.children[i], n.children[j]
+		if a.isWild != b.isWild {
+			return !a.isWild
+		}
+		if !a.isWild {
+			return a.segs[0] < b.segs[0]
+		}
+		if a.wildRank() != b.wildRank() {
+			return a.wildRank() < b.wildRank()
+		}
+		return a.part < b.part
+	})
+}
+
+// conflict 方法用于在插入之前检查路由规则是否与已有的路由冲突，它沿着 insert 将要经过的路径查找，
+// 但不修改路由树，因此插入失败时路由树保持不变
+func (n *node) conflict(pattern string, parts []string, height int) error {
+	if len(parts) == height {
+		if n.pattern != "" {
+			return fmt.Errorf("route '%s' conflicts with existing route '%s'", pattern, n.pattern)
+		}
+		// 可选参数缺省时的路径与当前路由相同
+		if child := n.optionalChild(); child != nil {
+			return fmt.Errorf("route '%s' conflicts with existing route '%s'", pattern, child.pattern)
+		}
+		return nil
+	}
+
+	part := parts[height]
+	if isOptionalPart(part) && n.pattern != "" {
+		return fmt.Errorf("route '%s' conflicts with existing route '%s'", pattern, n.pattern)
+	}
+	if isWildPart(part) {
+		// 同一位置已经存在名称不同、种类相同且都没有约束的通配符节点时，后注册的名称会被忽略，因此视为冲突；
+		// 无约束的参数节点和通配符 * 节点可以并存，通配符 * 节点作为最后的选择
+		if child := n.wildChild(part); child != nil {
+			return child.conflict(pattern, parts, height+1)
+		}
+		constrained := partRegexp(part) != nil
+		for _, child := range n.children {
+			if child.isWild && child.re == nil && !constrained && child.part[0] == part[0] {
+				return fmt.Errorf("wildcard '%s' in route '%s' conflicts with wildcard '%s' in existing route '%s'",
+					part, pattern, child.part, child.firstPattern())
+			}
+		}
+		return nil
+	}
+
+	// 静态部分只可能与完全覆盖其路径段的静态子节点下的路由冲突，需要拆分的节点之后都是新建的分支
 	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
+		if child.isWild || child.segs[0] != literal(part) {
+			continue
+		}
+		m := 1
+		for m < len(child.segs) && height+m < len(parts) && child.segs[m] == literal(parts[height+m]) {
+			m++
+		}
+		if m < len(child.segs) {
+			return nil
 		}
+		return child.conflict(pattern, parts, height+m)
 	}
 	return nil
 }
 
-// insert 方法用于向路由树中插入新的节点，并递归调用自身完成整个节点的插入过程
+// insert 方法用于向路由树中插入新的节点，并递归调用自身完成整个节点的插入过程。
+// 调用前需要先通过 conflict 确认不存在冲突
 func (n *node) insert(pattern string, parts []string, height int) {
 	// 如果当前已经到达最后一层，即parts 数组为空，则将节点的 pattern 字段设置为当前路由规则，
 	// 兵返回结束递归
@@ -35,45 +247,345 @@ func (n *node) insert(pattern string, parts []string, height int) {
 
 	// 否则，取出 parts 数组中当前层对应的部分 part， 并在当前节点的子节点中查找是否含有匹配的节点
 	part := parts[height]
-	child := n.matchChild(part)
+	if isWildPart(part) {
+		child := n.wildChild(part)
+		// 如果没有匹配的节点，则创建一个新节点，并将其添加到当前节点的子节点中
+		if child == nil {
+			child = n.newChild(part)
+		}
+		// 递归调用 insert 方法，将当前节点设置为子节点，高度加 1，继续向下一层递归
+		child.insert(pattern, parts, height+1)
+		return
+	}
+
+	// 静态部分：查找首段相同的静态子节点，只共享一部分路径段时先把该子节点拆分开。
+	// 静态部分不会落入通配符子节点，而是与之并列，匹配时静态节点优先
+	for i, child := range n.children {
+		if child.isWild || child.segs[0] != literal(part) {
+			continue
+		}
+		m := 1
+		for m < len(child.segs) && height+m < len(parts) && child.segs[m] == literal(parts[height+m]) {
+			m++
+		}
+		if m < len(child.segs) {
+			child = n.split(i, m)
+		}
+		child.insert(pattern, parts, height+m)
+		return
+	}
+
+	// 没有可复用的子节点时，把连续的静态部分合并成一个节点
+	end := height + 1
+	for end < len(parts) && !isWildPart(parts[end]) {
+		end++
+	}
+	segs := make([]string, 0, end-height)
+	for _, part := range parts[height:end] {
+		segs = append(segs, literal(part))
+	}
+	child := &node{part: strings.Join(segs, "/"), segs: segs}
+	n.addChild(child)
+	child.insert(pattern, parts, end)
+}
+
+// split 方法用于将第 i 个静态子节点在第 m 段处拆分为前后两个节点，返回前半部分对应的新节点
+func (n *node) split(i, m int) *node {
+	child := n.children[i]
+	head := &node{
+		part:     strings.Join(child.segs[:m], "/"),
+		segs:     child.segs[:m:m],
+		children: []*node{child},
+	}
+	child.segs = child.segs[m:]
+	child.part = strings.Join(child.segs, "/")
+	n.children[i] = head
+	return head
+}
+
+// wildRank 方法用于返回通配符节点在兄弟节点中的匹配优先级，数值越小越先尝试
+func (n *node) wildRank() int {
+	switch {
+	case n.part[0] == '*':
+		return 2
+	case n.re == nil:
+		return 1
+	}
+	return 0
+}
+
+// wildChild 方法用于在插入通配符部分时查找与 part 完全相同的子节点，不存在时返回 nil
+func (n *node) wildChild(part string) *node {
+	for _, child := range n.children {
+		if child.part == part {
+			return child
+		}
+	}
+	return nil
+}
+
+// remove 方法用于在以当前节点为根的子树中删除路由规则 pattern 对应的节点，
+// 并剪除不再通向任何路由规则的节点，返回是否删除成功
+func (n *node) remove(pattern string, parts []string, height int) bool {
+	if len(parts) == height {
+		if n.pattern != pattern {
+			return false
+		}
+		n.pattern = ""
+		n.where = nil
+		n.meta = nil
+		n.timeout = nil
+		return true
+	}
+
+	for i, child := range n.children {
+		k := child.exactMatch(parts, height)
+		if k == 0 {
+			continue
+		}
+		if !child.remove(pattern, parts, height+k) {
+			return false
+		}
+		if child.pattern == "" && len(child.children) == 0 {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+		} else {
+			child.merge()
+		}
+		return true
+	}
+	return false
+}
+
+// exactMatch 方法用于判断当前节点是否由 parts 从 height 开始的部分原样插入，返回对应的段数，否则返回 0
+func (n *node) exactMatch(parts []string, height int) int {
+	if n.isWild {
+		if n.part == parts[height] {
+			return 1
+		}
+		return 0
+	}
+	if len(parts)-height < len(n.segs) {
+		return 0
+	}
+	for i, seg := range n.segs {
+		if literal(parts[height+i]) != seg {
+			return 0
+		}
+	}
+	return len(n.segs)
+}
+
+// merge 方法用于在删除路由后，将没有路由规则且只剩一个静态子节点的静态节点与该子节点重新合并
+func (n *node) merge() {
+	if n.isWild || n.pattern != "" || len(n.children) != 1 || n.children[0].isWild {
+		return
+	}
+	child := n.children[0]
+	n.segs = append(n.segs[:len(n.segs):len(n.segs)], child.segs...)
+	n.part = strings.Join(n.segs, "/")
+	n.pattern = child.pattern
+	n.where = child.where
+	n.meta = child.meta
+	n.timeout = child.timeout
+	n.children = child.children
+}
+
+// optionalChild 方法用于返回注册了路由的可选参数子节点，不存在时返回 nil
+func (n *node) optionalChild() *node {
+	for _, child := range n.children {
+		if child.isWild && child.pattern != "" && isOptionalPart(child.part) {
+			return child
+		}
+	}
+	return nil
+}
 
-	// 如果没有匹配的节点，则创建一个新节点，并将其添加到当前节点的子节点中
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':'}
-		n.children = append(n.children, child)
+// walk 方法用于深度优先遍历以当前节点为根的子树，对每个节点调用 fn
+func (n *node) walk(fn func(*node)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
 	}
+}
 
-	// 递归调用 insert 方法，将当前节点设置为子节点，高度加 1，继续向下一层递归
-	child.insert(pattern, parts, height+1)
+// firstPattern 方法用于返回以当前节点为根的子树中第一个注册的路由规则，用于生成冲突提示信息
+func (n *node) firstPattern() string {
+	if n.pattern != "" {
+		return n.pattern
+	}
+	for _, child := range n.children {
+		if pattern := child.firstPattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return ""
 }
 
-// search 方法用于查找路由树中是否存在匹配的路由规则
-func (n *node) search(parts []string, height int) *node {
+// search 方法用于查找路由树中是否存在匹配的路由规则。parts 用于匹配静态部分，values 是与之一一对应的原始路径段，
+// 用于检查参数约束，两者通常是同一个切片；catchAllSlash 对应路由的 CatchAllSlash 选项，决定通配符的值是否以 / 开头
+func (n *node) search(parts, values []string, height int, catchAllSlash bool) *node {
 	// 如果当前已经到达最后一层，即parts 数组为空，则判断当前节点的 pattern 字段是否为空，
 	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.pattern == "" {
-			return nil
+		// 参数不满足 Where 设置的约束时视为不匹配，回溯尝试其他分支
+		if n.pattern != "" && n.satisfies(values, catchAllSlash) {
+			return n
 		}
-		return n
+		// 路径在可选参数之前结束时，由可选参数节点上的路由处理
+		if child := n.optionalChild(); child != nil && len(parts) == height && child.satisfies(values, catchAllSlash) {
+			return child
+		}
+		return nil
 	}
 
-	// 否则，取出 parts 数组中当前层对应的部分 part， 并在当前节点的子节点中查找是否含有匹配的节点
-	part := parts[height]
-	child := n.matchChild(part)
+	// 否则，先尝试匹配的静态子节点，再依次尝试通配符子节点；
+	// 某个分支的子树中没有匹配的路由规则时回溯，继续尝试下一个分支
+	for _, child := range n.children {
+		if !child.isWild {
+			if k := child.match(parts, values, height); k > 0 {
+				if result := child.search(parts, values, height+k, catchAllSlash); result != nil {
+					return result
+				}
+			}
+		}
+	}
+	for _, child := range n.children {
+		if child.isWild && child.match(parts, values, height) > 0 {
+			if result := child.search(parts, values, height+1, catchAllSlash); result != nil {
+				return result
+			}
+		}
+	}
 
 	// 如果没有匹配的节点，则返回 nil
-	if child == nil {
-		return nil
+	return nil
+}
+
+// searchFold 方法用于在忽略静态部分大小写的情况下查找所有匹配的路由，供 FixPath 使用。
+// 每找到一个匹配，就把静态部分替换为路由规则中的写法、其余部分保留 raw 中的原始写法，追加到 fixed 中
+func (n *node) searchFold(parts, raw []string, height int, catchAllSlash bool, buf []string, fixed *[][]string) {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern != "" && n.satisfies(parts, catchAllSlash) {
+			*fixed = append(*fixed, append(append([]string{}, buf...), raw[height:]...))
+			return
+		}
+		if child := n.optionalChild(); child != nil && len(parts) == height && child.satisfies(parts, catchAllSlash) {
+			*fixed = append(*fixed, append([]string{}, buf...))
+		}
+		return
+	}
+
+	for _, child := range n.children {
+		if child.isWild {
+			if child.match(parts, parts, height) > 0 {
+				child.searchFold(parts, raw, height+1, catchAllSlash, append(buf, raw[height]), fixed)
+			}
+			continue
+		}
+		if len(parts)-height < len(child.segs) {
+			continue
+		}
+		matched := true
+		for i, seg := range child.segs {
+			if !strings.EqualFold(parts[height+i], seg) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			child.searchFold(parts, raw, height+len(child.segs), catchAllSlash, append(buf, child.segs...), fixed)
+		}
 	}
+}
+
+// contextKey 是本包在请求上下文中使用的键类型，避免与其他包的键冲突
+type contextKey int
+
+const (
+	paramsKey     contextKey = iota // 路由参数在请求上下文中的键
+	patternKey                      // 匹配到的路由规则在请求上下文中的键
+	requestIDKey                    // 请求 ID 在请求上下文中的键
+	routerKey                       // 处理当前请求的路由在请求上下文中的键，供 Context 读取路由配置
+	stateKey                        // 请求级共享状态在请求上下文中的键，同一请求中创建的 Context 共享该状态
+	hostParamsKey                   // 主机名中捕获的参数在请求上下文中的键，由主机路由合并到路由参数中
+	metaKey                         // 匹配到的路由的元数据在请求上下文中的键
+)
 
-	// 递归调用 search 方法，将当前节点设置为子节点，高度加 1，继续向下一层递归
-	return child.search(parts, height+1)
+// MatchedPattern 函数用于获取处理当前请求的路由规则（例如 /users/:id），没有匹配到路由时返回空字符串。
+// 指标和日志可以按路由规则而不是具体路径分组，避免标签基数过高
+func MatchedPattern(req *http.Request) string {
+	if pattern, ok := req.Context().Value(patternKey).(string); ok {
+		return pattern
+	}
+	// 主机路由的规则在内层的请求上下文中，外层的全局中间件从共享的请求状态中读取
+	if state, ok := req.Context().Value(stateKey).(*requestState); ok {
+		pattern, _ := state.pattern.Load().(string)
+		return pattern
+	}
+	return ""
 }
 
-// router 结构体用于实现路由树的插入、查找和路由处理
+// router 结构体用于实现路由树的插入、查找和路由处理。
+// 路由表由读写锁保护，服务启动后继续注册路由（例如动态开启某些接口）也是安全的
 type router struct {
+	mu sync.RWMutex // 保护下面的路由表，注册路由时加写锁，查找路由时加读锁
+
 	roots    map[string]*node            // 用于存储不同 HTTP 方法对应的路由树的根节点
 	handlers map[string]http.HandlerFunc // 用于存储路由规则和对应的处理函数
+
+	middlewares []Middleware           // 全局中间件，按注册顺序包裹处理函数
+	ctxMws      []func(*Context)       // 通过 UseContext 注册的全局中间件，位于 middlewares 之内、处理函数之外
+	groups      map[string]*RouteGroup // 记录通过路由组注册的路由所属的路由组
+	anyRoutes   map[string]bool        // 通过 ANY 注册的路由，之后为单个方法显式注册时会覆盖它们
+	names       map[string]RouteInfo   // 路由名称到路由信息的映射，用于反向生成 URL
+	hosts       map[string]*router     // 主机名到主机路由的映射，通过 Host 方法创建
+	hostTree    *node                  // 由倒序的主机名标签构成的主机树，用于匹配带参数的主机名
+	notFound    http.Handler           // 自定义的 404 处理器，为空时根据 Accept 头返回纯文本或 JSON 的 404 响应
+	notAllowed  http.Handler           // 自定义的 405 处理器，为空时根据 Accept 头返回纯文本或 JSON 的 405 响应
+	onError     func(*Context, error)  // 自定义的错误处理函数，处理 GETErr 等注册的处理函数返回的错误
+	server      *http.Server           // 由 Run 和 Serve 启动的 HTTP 服务，用于 Shutdown
+
+	HandleHEAD    bool // HEAD 请求没有对应处理函数时，是否回退到 GET 路由处理，默认开启
+	HandleOPTIONS bool // OPTIONS 请求没有对应处理函数时，是否自动返回 Allow 头，默认开启
+
+	// RedirectTrailingSlash 开启后，请求路径与路由规则仅尾部斜杠不同时，重定向到路由规则对应的规范路径
+	RedirectTrailingSlash bool
+
+	// FixPath 开启后，请求路径找不到路由、但忽略大小写后恰好匹配一条路由时，重定向到大小写规范的路径，
+	// 例如 /Users 重定向到 /users；忽略大小写后匹配到多条路由时不重定向。开启 CaseInsensitive 时该选项不起作用
+	FixPath bool
+
+	// CleanPath 开启后，包含连续斜杠或 . 和 .. 路径段的请求会被重定向到规范路径，例如 /hello//bob 重定向到 /hello/bob；
+	// 关闭时（默认）这样的请求直接返回 400，不会被当作规范路径匹配路由
+	CleanPath bool
+
+	// StrictSlash 开启后，/users/ 和 /users 是两条不同的路由，请求路径的尾部斜杠必须与路由规则一致；
+	// 关闭时（默认）尾部斜杠在注册和查找时都会被忽略，同时注册两者会因为路由冲突而 panic。
+	// 该选项需要在注册路由之前设置
+	StrictSlash bool
+
+	// CaseInsensitive 开启后，路由规则中的静态部分和请求路径均按小写匹配，参数值保留原始大小写。
+	// 该选项需要在注册路由之前设置
+	CaseInsensitive bool
+
+	// CatchAllSlash 开启后，通配符参数捕获的值以 / 开头，例如 /files/*path 匹配 /files/foo/bar 时 path 为 /foo/bar，
+	// 便于直接作为文件路径或代理路径使用；关闭时（默认）path 为 foo/bar
+	CatchAllSlash bool
+
+	// AllowCustomMethods 开启后，可以为 knownMethods 之外的方法（例如 WebDAV 的 PROPFIND）注册路由；
+	// 关闭时（默认）注册未知方法会返回错误，避免拼写错误的方法生成一棵永远无法匹配的路由树
+	AllowCustomMethods bool
+
+	MaxMultipartMemory int64 // 解析 multipart 表单时保存在内存中的最大字节数，默认 32MB
+
+	// MaxPathDepth 大于 0 时，路径段数超过该值的请求直接返回 400，不再查找路由，
+	// 避免恶意构造的超长路径消耗过多资源；为 0 时（默认）不限制
+	MaxPathDepth int
+
+	templates *template.Template // 通过 LoadHTMLGlob 加载的 HTML 模板，供 Context.HTML 使用
+	metrics   *metrics           // Metrics 中间件收集的请求指标，通过 MetricsHandler 导出
+
+	trustedProxies []*net.IPNet  // 通过 SetTrustedProxies 设置的可信代理网段，ClientIP 只信任来自这些地址的转发头
+	defaultTimeout time.Duration // 通过 SetDefaultTimeout 设置的请求 context 默认超时时间，为 0 时不设置
 }
 
 // newRouter 方法用于创建一个路由树
@@ -81,6 +593,17 @@ func newRouter() *router {
 	return &router{
 		roots:    make(map[string]*node),            // 初始化 roots 字段 存储不同 HTTP 方法对应的路由树的根节点
 		handlers: make(map[string]http.HandlerFunc), // 初始化 handlers 字段 用于存储路由规则和对应的处理函数
+		groups:   make(map[string]*RouteGroup),      // 初始化 groups 字段 用于记录路由所属的路由组
+		names:    make(map[string]RouteInfo),        // 初始化 names 字段 用于记录路由名称
+
+		anyRoutes: make(map[string]bool),
+
+		HandleHEAD:    true,
+		HandleOPTIONS: true,
+
+		MaxMultipartMemory: defaultMultipartMemory,
+
+		metrics: newMetrics(),
 	}
 }
 
@@ -99,39 +622,623 @@ func parsePattern(pattern string) []string {
 	return result
 }
 
+// slashMarker 是 StrictSlash 开启时表示尾部斜杠的路径段。路径按 / 切分后不会出现这样的段，因此不会与真实路径段混淆
+const slashMarker = "/"
+
+// splitPath 方法用于将路由规则或请求路径切分为路径段。StrictSlash 开启时，
+// 以 / 结尾的路径会在末尾追加 slashMarker，使 /users/ 与 /users 成为不同的路径；以通配符结尾的路由规则不追加
+func (r *router) splitPath(path string) []string {
+	parts := parsePattern(path)
+	if r.StrictSlash && len(parts) > 0 && strings.HasSuffix(path, "/") && parts[len(parts)-1][0] != '*' {
+		parts = append(parts, slashMarker)
+	}
+	return parts
+}
+
+// splitRequestPath 方法用于将请求路径切分为路径段并追加到 dst 中，调用方可以传入栈上的数组以避免查找路由时分配内存。
+// 与 splitPath 不同，请求路径中以 * 开头的路径段只是普通的值，不会截断其后的路径段
+func (r *router) splitRequestPath(dst []string, path string) []string {
+	for rest := path; rest != ""; {
+		i := strings.IndexByte(rest, '/')
+		if i < 0 {
+			dst = append(dst, rest)
+			break
+		}
+		if i > 0 {
+			dst = append(dst, rest[:i])
+		}
+		rest = rest[i+1:]
+	}
+	if r.StrictSlash && len(dst) > 0 && strings.HasSuffix(path, "/") {
+		dst = append(dst, slashMarker)
+	}
+	return dst
+}
+
+// catchAllValue 函数用于将通配符匹配的路径段拼接为参数值，slash 为 true 时值以 / 开头。
+// StrictSlash 开启时请求路径末尾的斜杠标记还原为值末尾的 /。参数约束和处理函数看到的都是该函数返回的值
+func catchAllValue(rest []string, slash bool) string {
+	suffix := ""
+	if last := len(rest) - 1; last >= 0 && rest[last] == slashMarker {
+		rest, suffix = rest[:last], "/"
+	}
+	value := strings.Join(rest, "/") + suffix
+	if slash {
+		value = "/" + value
+	}
+	return value
+}
+
+// pathDepth 函数用于统计路径中非空路径段的数量，与 splitRequestPath 的切分规则一致，
+// 末尾的斜杠和连续的斜杠不计入段数，例如 /a/b/ 的段数为 2
+func pathDepth(path string) int {
+	depth := 0
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' && (i == 0 || path[i-1] == '/') {
+			depth++
+		}
+	}
+	return depth
+}
+
+// validatePattern 函数用于检查路由规则的语法：必须以 / 开头，参数和通配符必须有名称，
+// 必须是合法的 UTF-8，: 和 * 只能出现在路径段的开头，通配符只能是最后一段，正则约束必须能够编译
+func validatePattern(pattern string) error {
+	if pattern == "" || pattern[0] != '/' {
+		return fmt.Errorf("route '%s': pattern must begin with '/'", pattern)
+	}
+	if !utf8.ValidString(pattern) {
+		return fmt.Errorf("route %q: pattern is not valid UTF-8", pattern)
+	}
+
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		if seg == "" {
+			continue
+		}
+		switch seg[0] {
+		case ':':
+			if isCompositePart(seg) {
+				names, seps := parseComposite(seg)
+				for j, name := range names {
+					if name == "" || strings.ContainsAny(seps[j], "()*?") || j < len(names)-1 && seps[j] == "" {
+						return fmt.Errorf("route '%s': invalid multi-param segment '%s'", pattern, seg)
+					}
+				}
+				continue
+			}
+			name, expr := splitParam(seg)
+			if name == "" || strings.ContainsAny(name, ":*()?") {
+				return fmt.Errorf("route '%s': invalid param name in segment '%s'", pattern, seg)
+			}
+			if isOptionalPart(seg) {
+				for _, rest := range segs[i+1:] {
+					if rest != "" {
+						return fmt.Errorf("route '%s': optional param '%s' must be the last segment", pattern, seg)
+					}
+				}
+			}
+			if expr != "" {
+				if _, err := regexp.Compile(expr); err != nil {
+					return fmt.Errorf("route '%s': invalid regexp in segment '%s': %v", pattern, seg, err)
+				}
+			}
+		case '*':
+			if name := seg[1:]; name == "" || strings.ContainsAny(name, ":*") {
+				return fmt.Errorf("route '%s': invalid catch-all name in segment '%s'", pattern, seg)
+			}
+			for _, rest := range segs[i+1:] {
+				if rest != "" {
+					return fmt.Errorf("route '%s': catch-all '%s' must be the last segment", pattern, seg)
+				}
+			}
+		default:
+			// 经 \ 转义的 : 和 * 是字面字符，可以出现在静态部分的任意位置
+			if strings.ContainsAny(escapeStripper.Replace(seg), ":*") {
+				return fmt.Errorf("route '%s': ':' and '*' are only allowed at the start of a segment '%s' (use \\: or \\* for literals)", pattern, seg)
+			}
+		}
+	}
+	return nil
+}
+
+// knownMethods 是未开启 AllowCustomMethods 时允许注册路由的 HTTP 方法
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// validateMethod 方法用于校验注册路由时使用的 HTTP 方法（已转换为大写）。空方法和含有非法字符的方法总是无效，
+// 未开启 AllowCustomMethods 时还必须是 knownMethods 中的方法
+func (r *router) validateMethod(method string) error {
+	if method == "" {
+		return fmt.Errorf("route method must not be empty")
+	}
+	if r.AllowCustomMethods {
+		// 方法名必须是 RFC 9110 定义的 token
+		for _, c := range method {
+			if c > unicode.MaxASCII || !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("!#$%&'*+-.^_`|~", c) {
+				return fmt.Errorf("invalid route method %q", method)
+			}
+		}
+		return nil
+	}
+	if !knownMethods[method] {
+		return fmt.Errorf("unknown route method %q (set AllowCustomMethods to register it)", method)
+	}
+	return nil
+}
+
+// lowerParts 函数用于返回将静态部分转换为小写后的新切片，参数和通配符部分保持不变
+func lowerParts(parts []string) []string {
+	result := make([]string, len(parts))
+	for i, part := range parts {
+		if isWildPart(part) {
+			result[i] = part
+		} else {
+			result[i] = strings.ToLower(part)
+		}
+	}
+	return result
+}
+
+// addRoute 方法用于注册路由，可以在多个 goroutine 中并发调用
 func (r *router) addRoute(method, pattern string, handler http.HandlerFunc) {
-	parts := parsePattern(pattern)
+	if err := r.AddRouteE(method, pattern, handler); err != nil {
+		panic(err.Error())
+	}
+}
+
+// AddRouteE 方法用于注册路由，路由规则语法错误、重复注册或与已有路由冲突时返回错误而不是 panic。
+// 注册失败时路由树和处理函数表都保持不变，可以在多个 goroutine 中并发调用
+func (r *router) AddRouteE(method, pattern string, handler http.HandlerFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.insertRouteE(method, pattern, handler)
+	return err
+}
+
+// insertRoute 方法用于将路由插入路由树并记录处理函数，返回该路由在 handlers 中的键，注册失败时 panic。调用方需要持有写锁
+func (r *router) insertRoute(method, pattern string, handler http.HandlerFunc) string {
+	key, err := r.insertRouteE(method, pattern, handler)
+	if err != nil {
+		panic(err.Error())
+	}
+	return key
+}
+
+// insertRouteE 方法用于将路由插入路由树并记录处理函数，返回该路由在 handlers 中的键。
+// 所有检查都在修改路由树之前完成，返回错误时不会留下任何改动。调用方需要持有写锁
+func (r *router) insertRouteE(method, pattern string, handler http.HandlerFunc) (string, error) {
+	// HTTP 方法统一转换为大写，避免 "get" 与 "GET" 被注册到不同的路由树中
+	method = strings.ToUpper(method)
+	if err := r.validateMethod(method); err != nil {
+		return "", err
+	}
+	if err := validatePattern(pattern); err != nil {
+		return "", err
+	}
+	parts := r.splitPath(pattern)
 
 	key := method + "-" + pattern
-	_, ok := r.roots[method]
+	if _, ok := r.handlers[key]; ok {
+		if !r.anyRoutes[key] {
+			return "", fmt.Errorf("duplicate route registration: %s %s", method, pattern)
+		}
+		// 覆盖通过 ANY 注册的路由：路由树中的节点保持不变，只替换处理函数，原有的路由组归属一并清除
+		delete(r.anyRoutes, key)
+		delete(r.groups, key)
+		r.handlers[key] = handler
+		return key, nil
+	}
+	if r.CaseInsensitive {
+		parts = lowerParts(parts)
+	}
+	root, ok := r.roots[method]
 	if !ok {
-		r.roots[method] = &node{}
+		root = &node{}
 	}
-	r.roots[method].insert(pattern, parts, 0)
+	if err := root.conflict(pattern, parts, 0); err != nil {
+		return "", err
+	}
+	r.roots[method] = root
+	root.insert(pattern, parts, 0)
 	r.handlers[key] = handler
+	return key, nil
+}
+
+// RemoveRoute 方法用于删除已注册的路由，返回是否删除成功。删除后路由树中不再通向任何路由的节点会被剪除
+func (r *router) RemoveRoute(method, pattern string) bool {
+	method = strings.ToUpper(method)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.removeRoute(method, pattern)
+}
+
+// removeRoute 方法用于从路由树和各个路由表中删除路由，method 需要已经转换为大写。调用方需要持有写锁
+func (r *router) removeRoute(method, pattern string) bool {
+	key := method + "-" + pattern
+	root, ok := r.roots[method]
+	if _, exists := r.handlers[key]; !ok || !exists {
+		return false
+	}
+
+	parts := r.splitPath(pattern)
+	if r.CaseInsensitive {
+		parts = lowerParts(parts)
+	}
+	if !root.remove(pattern, parts, 0) {
+		return false
+	}
+
+	delete(r.handlers, key)
+	delete(r.groups, key)
+	delete(r.anyRoutes, key)
+	for name, info := range r.names {
+		if info.Method == method && info.Pattern == pattern {
+			delete(r.names, name)
+		}
+	}
+	return true
+}
+
+// GET 方法用于注册 GET 请求的路由，返回的 Route 可以继续设置参数约束等选项
+func (r *router) GET(pattern string, handler http.HandlerFunc) *Route {
+	r.addRoute(http.MethodGet, pattern, handler)
+	return &Route{Method: http.MethodGet, Pattern: pattern, Handler: handler, router: r}
+}
+
+// POST 方法用于注册 POST 请求的路由，返回的 Route 可以继续设置参数约束等选项
+func (r *router) POST(pattern string, handler http.HandlerFunc) *Route {
+	r.addRoute(http.MethodPost, pattern, handler)
+	return &Route{Method: http.MethodPost, Pattern: pattern, Handler: handler, router: r}
+}
+
+// PUT 方法用于注册 PUT 请求的路由，返回的 Route 可以继续设置参数约束等选项
+func (r *router) PUT(pattern string, handler http.HandlerFunc) *Route {
+	r.addRoute(http.MethodPut, pattern, handler)
+	return &Route{Method: http.MethodPut, Pattern: pattern, Handler: handler, router: r}
+}
+
+// DELETE 方法用于注册 DELETE 请求的路由，返回的 Route 可以继续设置参数约束等选项
+func (r *router) DELETE(pattern string, handler http.HandlerFunc) *Route {
+	r.addRoute(http.MethodDelete, pattern, handler)
+	return &Route{Method: http.MethodDelete, Pattern: pattern, Handler: handler, router: r}
+}
+
+// PATCH 方法用于注册 PATCH 请求的路由，返回的 Route 可以继续设置参数约束等选项
+func (r *router) PATCH(pattern string, handler http.HandlerFunc) *Route {
+	r.addRoute(http.MethodPatch, pattern, handler)
+	return &Route{Method: http.MethodPatch, Pattern: pattern, Handler: handler, router: r}
+}
+
+// addRouteMany 方法用于为多个路由规则注册同一个处理函数，例如把 /、/index 和 /home 注册为同一个页面的别名。
+// 任意一个路由规则无效或与已有路由（包括前面的别名）冲突时，撤销本次的改动后 panic：
+// 新注册的路由被删除，被覆盖的 ANY 路由恢复原来的处理函数，调用之前已经存在的路由不受影响
+func (r *router) addRouteMany(method string, patterns []string, handler http.HandlerFunc) {
+	method = strings.ToUpper(method)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// overridden 记录本次覆盖的 ANY 路由原来的处理函数和路由组，撤销时恢复
+	type anyRoute struct {
+		handler http.HandlerFunc
+		group   *RouteGroup
+	}
+	overridden := make(map[string]anyRoute)
+	for i, pattern := range patterns {
+		key := method + "-" + pattern
+		if h, ok := r.handlers[key]; ok && r.anyRoutes[key] {
+			overridden[key] = anyRoute{h, r.groups[key]}
+		}
+		if _, err := r.insertRouteE(method, pattern, handler); err != nil {
+			for _, registered := range patterns[:i] {
+				key := method + "-" + registered
+				prev, ok := overridden[key]
+				if !ok {
+					r.removeRoute(method, registered)
+					continue
+				}
+				r.handlers[key] = prev.handler
+				r.anyRoutes[key] = true
+				if prev.group != nil {
+					r.groups[key] = prev.group
+				}
+			}
+			panic(err.Error())
+		}
+	}
+}
+
+// GETMany 方法用于为多个路由规则注册同一个 GET 请求处理函数
+func (r *router) GETMany(patterns []string, handler http.HandlerFunc) {
+	r.addRouteMany(http.MethodGet, patterns, handler)
+}
+
+// POSTMany 方法用于为多个路由规则注册同一个 POST 请求处理函数
+func (r *router) POSTMany(patterns []string, handler http.HandlerFunc) {
+	r.addRouteMany(http.MethodPost, patterns, handler)
+}
+
+// PUTMany 方法用于为多个路由规则注册同一个 PUT 请求处理函数
+func (r *router) PUTMany(patterns []string, handler http.HandlerFunc) {
+	r.addRouteMany(http.MethodPut, patterns, handler)
+}
+
+// DELETEMany 方法用于为多个路由规则注册同一个 DELETE 请求处理函数
+func (r *router) DELETEMany(patterns []string, handler http.HandlerFunc) {
+	r.addRouteMany(http.MethodDelete, patterns, handler)
 }
 
+// PATCHMany 方法用于为多个路由规则注册同一个 PATCH 请求处理函数
+func (r *router) PATCHMany(patterns []string, handler http.HandlerFunc) {
+	r.addRouteMany(http.MethodPatch, patterns, handler)
+}
+
+// anyMethods 是 ANY 注册路由时使用的 HTTP 方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
+// ANY 方法用于为 anyMethods 中的所有 HTTP 方法注册同一个处理函数。
+// 之后为某个方法显式注册同一路由规则时，会覆盖该方法下由 ANY 注册的处理函数
+func (r *router) ANY(pattern string, handler http.HandlerFunc) {
+	for _, method := range anyMethods {
+		r.addRoute(method, pattern, handler)
+		r.mu.Lock()
+		r.anyRoutes[method+"-"+pattern] = true
+		r.mu.Unlock()
+	}
+}
+
+// addRouteNamed 方法用于注册一个带名称的路由，之后可以通过 URL 方法根据名称反向生成路径
+func (r *router) addRouteNamed(method, pattern, name string, handler http.HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.insertRoute(method, pattern, handler)
+	r.names[name] = RouteInfo{Method: strings.ToUpper(method), Pattern: pattern, Name: name}
+}
+
+// URL 方法用于根据路由名称和参数生成具体的请求路径，名称未知或缺少参数时返回错误
+func (r *router) URL(name string, params map[string]string) (string, error) {
+	r.mu.RLock()
+	info, ok := r.names[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("route %q not found", name)
+	}
+
+	parts := parsePattern(info.Pattern)
+	for i, part := range parts {
+		if !isWildPart(part) {
+			parts[i] = literal(part)
+			continue
+		}
+		// 没有提供值的可选参数连同所在的路径段一起省略
+		if isOptionalPart(part) {
+			if name, _ := splitParam(part); params[name] == "" {
+				parts = parts[:i]
+				break
+			}
+		}
+		keys, seps := []string{part[1:]}, []string{""}
+		if isCompositePart(part) {
+			keys, seps = parseComposite(part)
+		} else if part[0] == ':' {
+			keys[0], _ = splitParam(part)
+		}
+
+		var b strings.Builder
+		for j, key := range keys {
+			value, ok := params[key]
+			if !ok {
+				return "", fmt.Errorf("route %q: missing param %q", name, key)
+			}
+			if part[0] == '*' {
+				// 通配符的值可以带有 CatchAllSlash 形式的前导 /，与前面的 / 合并，避免生成 //
+				value = strings.TrimPrefix(value, "/")
+			}
+			b.WriteString(value)
+			b.WriteString(seps[j])
+		}
+		parts[i] = b.String()
+	}
+	return "/" + strings.Join(parts, "/"), nil
+}
+
+// requestPath 函数用于返回请求路径的转义形式，这样被编码的 / (%2F) 不会被当作分隔符，
+// 参数值在 getRoute 中逐段解码
+func requestPath(req *http.Request) string {
+	if req.URL.RawPath != "" {
+		return req.URL.RawPath
+	}
+	return req.URL.EscapedPath()
+}
+
+// unescapeParts 函数用于对切分后的路径逐段进行 URL 解码
+func unescapeParts(parts []string) ([]string, error) {
+	result := make([]string, len(parts))
+	for i, part := range parts {
+		value, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// Mount 方法用于将独立构建的子路由挂载到 prefix 前缀下，子路由的每条路由规则都会加上该前缀。
+// 子路由的全局中间件和路由组中间件在挂载时固定到处理函数上，Where 约束、路由元数据和超时时间一并复制，与已有路由冲突时会 panic
+func (r *router) Mount(prefix string, sub *router) {
+	routes := sub.Routes()
+	handlers := make([]http.HandlerFunc, len(routes))
+	wheres := make([]map[string][]ParamConstraint, len(routes))
+	metas := make([]map[string]interface{}, len(routes))
+	timeouts := make([]*time.Duration, len(routes))
+	sub.mu.RLock()
+	for i, route := range routes {
+		key := route.Method + "-" + route.Pattern
+		mws := append(append([]Middleware{}, sub.middlewares...), sub.groups[key].allMiddlewares()...)
+		handlers[i] = chain(contextChain(sub.handlers[key], sub.ctxMws), mws)
+		if n := sub.routeNode(route.Method, route.Pattern); n != nil {
+			wheres[i] = copyWhere(n.where)
+			// 元数据修改时整体替换，可以直接共享
+			metas[i] = n.meta
+			timeouts[i] = n.timeout
+		}
+	}
+	sub.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, route := range routes {
+		pattern := prefix + route.Pattern
+		if route.Pattern == "/" {
+			pattern = prefix
+		}
+		r.insertRoute(route.Method, pattern, handlers[i])
+		// 子路由上通过 Where 设置的约束、WithMeta 附加的元数据和 WithTimeout 设置的超时时间随路由一起挂载，
+		// 参数名不变，因此可以直接沿用
+		if n := r.routeNode(route.Method, pattern); n != nil {
+			n.where, n.meta, n.timeout = wheres[i], metas[i], timeouts[i]
+		}
+		if route.Name != "" {
+			r.names[route.Name] = RouteInfo{Method: route.Method, Pattern: pattern, Name: route.Name}
+		}
+	}
+}
+
+// Handle 方法用于将标准库的 http.Handler 注册为 method 请求的路由，
+// 便于直接复用已有的 net/http 处理器，返回的 Route 可以继续设置参数约束等选项
+func (r *router) Handle(method, pattern string, h http.Handler) *Route {
+	method = strings.ToUpper(method)
+	r.addRoute(method, pattern, h.ServeHTTP)
+	return &Route{Method: method, Pattern: pattern, Handler: h.ServeHTTP, router: r}
+}
+
+// HandlePrefix 方法用于将 http.Handler（例如一个 http.ServeMux）挂载到 prefix 前缀下，
+// 所有方法的请求都会交给 h 处理，交给 h 之前会去掉路径中的前缀。
+// 例如挂载到 /legacy 后，/legacy/users 在 h 中看到的路径为 /users，/legacy 本身对应 /
+func (r *router) HandlePrefix(prefix string, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		params := req.Context().Value(paramsKey).(map[string]string)
+		// 与 http.StripPrefix 一样复制请求，避免修改调用方持有的 URL
+		req = req.Clone(req.Context())
+		req.URL.Path = "/" + strings.TrimPrefix(params["path"], "/")
+		req.URL.RawPath = ""
+		h.ServeHTTP(w, req)
+	}
+
+	r.ANY(prefix+"/*path", handler)
+	if prefix != "" {
+		r.ANY(prefix, handler)
+	}
+}
+
+// RouteInfo 结构体描述一条已注册的路由
+type RouteInfo struct {
+	Method  string // HTTP 方法
+	Pattern string // 路由规则
+	Name    string // 路由名称，未命名的路由为空
+}
+
+// Routes 方法用于列出所有已注册的路由，结果先按 HTTP 方法、再按路由规则排序
+func (r *router) Routes() []RouteInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make(map[string]string, len(r.names))
+	for name, info := range r.names {
+		names[info.Method+"-"+info.Pattern] = name
+	}
+
+	routes := make([]RouteInfo, 0, len(r.handlers))
+	for method, root := range r.roots {
+		root.walk(func(n *node) {
+			if n.pattern != "" {
+				routes = append(routes, RouteInfo{Method: method, Pattern: n.pattern, Name: names[method+"-"+n.pattern]})
+			}
+		})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Pattern < routes[j].Pattern
+	})
+	return routes
+}
+
+// maxStackParts 是查找路由时在栈上预留的路径段数量，路径段更多的请求才需要在堆上分配
+const maxStackParts = 16
+
+// getRoute 方法用于根据请求方法和转义后的请求路径查找路由节点，并返回解码后的路由参数。
+// 不含转义字符的请求匹配到静态路由时不分配内存
 func (r *router) getRoute(method, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
+	var buf [maxStackParts]string
+	searchParts := r.splitRequestPath(buf[:0], path)
+	if strings.IndexByte(path, '%') >= 0 {
+		var err error
+		if searchParts, err = unescapeParts(searchParts); err != nil {
+			return nil, nil
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
 	root, ok := r.roots[method]
 	if !ok {
 		return nil, nil
 	}
 
-	n := root.search(searchParts, 0)
+	matchParts := searchParts
+	if r.CaseInsensitive {
+		matchParts = lowerParts(searchParts)
+	}
+
+	n := root.search(matchParts, searchParts, 0, r.CatchAllSlash)
 	if n == nil {
 		return nil, nil
 	}
 
+	// 只有带参数的路由才从对象池中取出 map，静态路由直接返回 nil，避免内存分配
+	if strings.IndexAny(n.pattern, ":*") < 0 {
+		return n, nil
+	}
+	var params map[string]string
 	parts := parsePattern(n.pattern)
 	for i, part := range parts {
 		if part[0] == ':' {
-			params[part[1:]] = searchParts[i]
+			if params == nil {
+				params = getParams()
+			}
+			if isCompositePart(part) {
+				names, _ := parseComposite(part)
+				if m := compositeRegexp(part).FindStringSubmatch(searchParts[i]); m != nil {
+					for j, name := range names {
+						params[name] = m[j+1]
+					}
+				}
+				continue
+			}
+			name, _ := splitParam(part)
+			if i >= len(searchParts) {
+				// 缺省的可选参数同样出现在路由参数中，值为空字符串
+				params[name] = ""
+				continue
+			}
+			params[name] = searchParts[i]
 		}
 		if part[0] == '*' && len(part) > 1 {
-			params[part[1:]] = strings.Join(searchParts[i:], "/")
+			if params == nil {
+				params = getParams()
+			}
+			params[part[1:]] = catchAllValue(searchParts[i:], r.CatchAllSlash)
 			break
 		}
 	}
@@ -139,16 +1246,405 @@ func (r *router) getRoute(method, path string) (*node, map[string]string) {
 	return n, params
 }
 
+// paramsPool 用于复用路由参数 map，减少每次请求的内存分配
+var paramsPool = sync.Pool{
+	New: func() interface{} {
+		return make(map[string]string)
+	},
+}
+
+// getParams 函数用于从对象池中取出一个空的路由参数 map
+func getParams() map[string]string {
+	return paramsPool.Get().(map[string]string)
+}
+
+// putParams 函数用于清空路由参数 map 并放回对象池，params 为 nil 时不做任何处理
+func putParams(params map[string]string) {
+	if params == nil {
+		return
+	}
+	for key := range params {
+		delete(params, key)
+	}
+	paramsPool.Put(params)
+}
+
+// allowedMethods 方法用于扫描所有 HTTP 方法的路由树，收集能够匹配指定路径的方法，结果按字母排序
+func (r *router) allowedMethods(path string) []string {
+	r.mu.RLock()
+	candidates := make([]string, 0, len(r.roots))
+	for method := range r.roots {
+		candidates = append(candidates, method)
+	}
+	r.mu.RUnlock()
+
+	methods := make([]string, 0)
+	for _, method := range candidates {
+		if n, params := r.getRoute(method, path); n != nil {
+			putParams(params)
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// NotFound 方法用于设置找不到路由时的处理器，例如渲染自定义的 404 页面。
+// 与匹配到的路由一样，它会被全局中间件包裹，日志和指标等中间件同样能观察到 404 响应
+func (r *router) NotFound(handler http.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notFound = handler
+}
+
+// handleNotFound 方法用于响应 404，优先使用自定义的处理器
+func (r *router) handleNotFound(c http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	handler := r.notFound
+	r.mu.RUnlock()
+	if handler == nil {
+		writeFallbackError(c, req, http.StatusNotFound, "404 page not found")
+		return
+	}
+	handler.ServeHTTP(c, req)
+}
+
+// MethodNotAllowed 方法用于设置路径存在但请求方法不匹配时的处理器，
+// 调用该处理器前响应中已经设置好 Allow 头。它同样会被全局中间件包裹
+func (r *router) MethodNotAllowed(handler http.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notAllowed = handler
+}
+
+// handleMethodNotAllowed 方法用于响应 405，优先使用自定义的处理器
+func (r *router) handleMethodNotAllowed(c http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	handler := r.notAllowed
+	r.mu.RUnlock()
+	if handler == nil {
+		writeFallbackError(c, req, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+	handler.ServeHTTP(c, req)
+}
+
+// writeFallbackError 函数用于写出默认的 404、405 响应。请求的 Accept 头更偏好 JSON 时返回
+// {"error":"not found","path":"/x"} 这样的 JSON 错误对象，否则返回纯文本 text
+func writeFallbackError(w http.ResponseWriter, req *http.Request, code int, text string) {
+	c := newContext(w, req)
+	if c.Negotiate("text/plain", "application/json") == "application/json" {
+		c.JSON(code, map[string]string{
+			"error": strings.ToLower(http.StatusText(code)),
+			"path":  req.URL.Path,
+		})
+		return
+	}
+	http.Error(w, text, code)
+}
+
+// LoadHTMLGlob 方法用于加载匹配 pattern 的所有 HTML 模板，模板语法错误时 panic
+func (r *router) LoadHTMLGlob(pattern string) {
+	templates := template.Must(template.ParseGlob(pattern))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates = templates
+}
+
+// headResponseWriter 用于 HEAD 请求回退到 GET 处理函数时丢弃响应体，
+// 同时统计写入的字节数，以便在结束时补上 Content-Length 头
+type headResponseWriter struct {
+	http.ResponseWriter
+	status int // 处理函数设置的状态码
+	size   int // 被丢弃的响应体字节数
+}
+
+func (w *headResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *headResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.size += len(p)
+	return len(p), nil
+}
+
+// finish 方法在处理函数返回后写出真正的响应头
+func (w *headResponseWriter) finish() {
+	if w.status == 0 {
+		return
+	}
+	if w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// redirectTrailingSlash 方法用于在请求路径与匹配到的路由规则只有尾部斜杠不同时，重定向到规范路径。
+// GET 和 HEAD 请求使用 301，其他方法使用 308 以保留请求方法和请求体。
+// 通配符路由会捕获剩余的整个路径，因此不做处理
+func (r *router) redirectTrailingSlash(c http.ResponseWriter, req *http.Request, path string, n *node) bool {
+	if path == "/" || strings.Contains(n.pattern, "*") {
+		return false
+	}
+
+	wantSlash := strings.HasSuffix(n.pattern, "/")
+	if strings.HasSuffix(path, "/") == wantSlash {
+		return false
+	}
+	if wantSlash {
+		path += "/"
+	} else {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	redirectPath(c, req, path)
+	return true
+}
+
+// toggleTrailingSlash 方法用于在请求路径没有匹配到路由时，检查添加或去掉尾部斜杠后能否匹配，
+// 能匹配时返回修改后的路径。HEAD 请求在开启 HandleHEAD 时同样检查 GET 路由
+func (r *router) toggleTrailingSlash(method, path string) (string, bool) {
+	if path == "/" {
+		return "", false
+	}
+	toggled := path + "/"
+	if strings.HasSuffix(path, "/") {
+		toggled = strings.TrimSuffix(path, "/")
+	}
+
+	n, params := r.getRoute(method, toggled)
+	if n == nil && method == http.MethodHead && r.HandleHEAD {
+		n, params = r.getRoute(http.MethodGet, toggled)
+	}
+	putParams(params)
+	return toggled, n != nil
+}
+
+// fixPath 方法用于在忽略静态部分大小写的情况下查找 path 对应的路由，只有唯一匹配时才返回大小写规范的路径
+func (r *router) fixPath(method, path string) (string, bool) {
+	raw := r.splitRequestPath(nil, path)
+	parts, err := unescapeParts(raw)
+	if err != nil {
+		return "", false
+	}
+
+	r.mu.RLock()
+	var fixed [][]string
+	if root, ok := r.roots[method]; ok {
+		root.searchFold(parts, raw, 0, r.CatchAllSlash, nil, &fixed)
+	}
+	// 与路由匹配一样，开启 HandleHEAD 时 HEAD 请求可以修正到 GET 路由
+	if len(fixed) == 0 && method == http.MethodHead && r.HandleHEAD {
+		if root, ok := r.roots[http.MethodGet]; ok {
+			root.searchFold(parts, raw, 0, r.CatchAllSlash, nil, &fixed)
+		}
+	}
+	r.mu.RUnlock()
+
+	// 同一个路径可能同时满足多条只有参数约束不同的路由，它们得到的规范路径相同，不算歧义
+	result := ""
+	for i, fixedParts := range fixed {
+		if last := len(fixedParts) - 1; last >= 0 && fixedParts[last] == slashMarker {
+			fixedParts = fixedParts[:last]
+		}
+		candidate := "/" + strings.Join(fixedParts, "/")
+		if strings.HasSuffix(path, "/") && candidate != "/" {
+			candidate += "/"
+		}
+		if i > 0 && candidate != result {
+			return "", false
+		}
+		result = candidate
+	}
+	return result, result != ""
+}
+
+// cleanPath 函数用于返回请求路径的规范形式：合并连续的斜杠并处理 . 和 .. 路径段，保留末尾的斜杠
+func cleanPath(p string) string {
+	if p == "" || p == "*" {
+		return p
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// redirectPath 函数用于将请求永久重定向到 path 并保留查询参数。GET 和 HEAD 请求使用 301，
+// 其他请求使用 308，保证客户端重发时不改变请求方法和请求体
+func redirectPath(c http.ResponseWriter, req *http.Request, path string) {
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	http.Redirect(c, req, path, code)
+}
+
+// ServeHTTP 方法用于分发请求，使 *router 实现 http.Handler，可以直接传给 http.ListenAndServe 或其他中间件
+func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handle(w, req)
+}
+
 func (r *router) handle(c http.ResponseWriter, req *http.Request) {
-	key := req.Method + "-" + req.URL.Path
+	method := req.Method
+	path := requestPath(req)
+	if _, err := url.PathUnescape(path); err != nil {
+		http.Error(c, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
 
+	// 包含连续斜杠或 . 和 .. 路径段的路径不直接参与匹配：开启 CleanPath 时重定向到规范路径，否则返回 400
+	if cleaned := cleanPath(path); cleaned != path {
+		if r.CleanPath {
+			redirectPath(c, req, cleaned)
+		} else {
+			http.Error(c, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		}
+		return
+	}
+
+	if r.MaxPathDepth > 0 && pathDepth(path) > r.MaxPathDepth {
+		http.Error(c, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	// 在进入任何中间件之前创建请求级共享状态，中间件调用 Context.Abort 后链上后续的步骤都能看到。
+	// 交给主机路由处理时沿用已有的状态
+	if _, ok := req.Context().Value(stateKey).(*requestState); !ok {
+		req = req.WithContext(context.WithValue(req.Context(), stateKey, &requestState{}))
+	}
+
+	// 请求的主机存在专属路由且能处理该路径时，交给主机路由处理，全局中间件同样生效
+	if hr, hostParams := r.hostRouter(req, path); hr != nil {
+		if hostParams != nil {
+			req = req.WithContext(context.WithValue(req.Context(), hostParamsKey, hostParams))
+		}
+		r.mu.RLock()
+		mws := append([]Middleware{}, r.middlewares...)
+		r.mu.RUnlock()
+		// 全局中间件属于当前路由，例如 Metrics 需要把指标记录到当前路由中；主机路由处理时会换成它自己
+		req = req.WithContext(context.WithValue(req.Context(), routerKey, r))
+		chain(hr.handle, mws)(c, req)
+		return
+	}
+
+	n, params := r.getRoute(method, path)
+
+	// HEAD 请求没有对应的路由时，回退到 GET 路由，并丢弃处理函数写入的响应体
+	if n == nil && method == http.MethodHead && r.HandleHEAD {
+		if n, params = r.getRoute(http.MethodGet, path); n != nil {
+			method = http.MethodGet
+			hw := &headResponseWriter{ResponseWriter: c}
+			defer hw.finish()
+			c = hw
+		}
+	}
+
+	// 没有匹配到路由时的自动 OPTIONS 响应、405 和 404 与匹配到的路由一样经过全局中间件，
+	// 这样 CORS 中间件可以处理预检请求，日志和指标中间件也能记录这些响应
+	if n == nil {
+		// StrictSlash 开启时 /users/ 和 /users 是不同的路由，请求路径只差尾部斜杠时同样重定向
+		if r.RedirectTrailingSlash {
+			if toggled, ok := r.toggleTrailingSlash(method, path); ok {
+				redirectPath(c, req, toggled)
+				return
+			}
+		}
+
+		allowed := r.allowedMethods(path)
+		if len(allowed) == 0 && r.FixPath && !r.CaseInsensitive {
+			if fixed, ok := r.fixPath(method, path); ok {
+				redirectPath(c, req, fixed)
+				return
+			}
+		}
+
+		var fallback http.HandlerFunc
+		switch {
+		// 没有显式注册 OPTIONS 路由时，自动以 204 响应并列出该路径允许的方法
+		case method == http.MethodOptions && r.HandleOPTIONS && len(allowed) > 0:
+			fallback = func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+				w.WriteHeader(http.StatusNoContent)
+			}
+		// 路径在其他 HTTP 方法下存在时返回 405，并通过 Allow 头告知允许的方法
+		case len(allowed) > 0:
+			fallback = func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Allow", strings.Join(allowed, ", "))
+				r.handleMethodNotAllowed(w, req)
+			}
+		default:
+			fallback = r.handleNotFound
+		}
+
+		r.mu.RLock()
+		mws := append([]Middleware{}, r.middlewares...)
+		r.mu.RUnlock()
+		req = req.WithContext(context.WithValue(req.Context(), routerKey, r))
+		chain(fallback, mws)(c, req)
+		return
+	}
+
+	// 主机名中捕获的参数合并到路由参数中，与路径参数同名时以路径参数为准
+	if hostParams, ok := req.Context().Value(hostParamsKey).(map[string]string); ok {
+		if params == nil {
+			params = getParams()
+		}
+		for name, value := range hostParams {
+			if _, exists := params[name]; !exists {
+				params[name] = value
+			}
+		}
+	}
+
+	// 处理函数返回后将路由参数 map 放回对象池，处理函数不应在返回后继续持有路由参数
+	defer putParams(params)
+
+	if r.RedirectTrailingSlash && r.redirectTrailingSlash(c, req, path, n) {
+		return
+	}
+
+	// 通过匹配到的路由规则（而不是请求路径）查找处理函数，这样动态路由才能生效
+	// 全局中间件位于最外层，其后依次是父级路由组和当前路由组的中间件。
+	// 读锁只在查表期间持有，这样处理函数内部也可以注册新的路由
+	key := method + "-" + n.pattern
+	r.mu.RLock()
 	handler, ok := r.handlers[key]
+	mws := append(append([]Middleware{}, r.middlewares...), r.groups[key].allMiddlewares()...)
+	ctxMws := r.ctxMws
+	meta := n.meta
+	timeout := r.defaultTimeout
+	if n.timeout != nil {
+		// 路由通过 WithTimeout 设置的超时时间优先于默认超时时间
+		timeout = *n.timeout
+	}
+	r.mu.RUnlock()
 	if !ok {
-		http.NotFound(c, req)
+		r.handleNotFound(c, req)
 		return
 	}
 
-	handler(c, req)
+	// 将解析出的路由参数、匹配到的路由规则及其元数据和当前路由注入到请求上下文中
+	ctx := context.WithValue(req.Context(), paramsKey, params)
+	ctx = context.WithValue(ctx, patternKey, n.pattern)
+	if state, ok := ctx.Value(stateKey).(*requestState); ok {
+		state.pattern.Store(n.pattern)
+	}
+	ctx = context.WithValue(ctx, metaKey, meta)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req = req.WithContext(context.WithValue(ctx, routerKey, r))
+	chain(contextChain(handler, ctxMws), mws)(c, req)
 }
 
 func main() {
@@ -157,11 +1653,15 @@ func main() {
 		fmt.Fprint(w, "Hello, World!")
 	})
 	r.addRoute("GET", "/hello/:name", func(w http.ResponseWriter, r *http.Request) {
-		params := r.Context().Value("params").(map[string]string)
+		params := r.Context().Value(paramsKey).(map[string]string)
 		fmt.Fprintf(w, "Hello, %s!", params["name"])
 	})
 	r.addRoute("GET", "/user/*action", func(w http.ResponseWriter, r *http.Request) {
-		params := r.Context().Value("params").(map[string]string)
+		params := r.Context().Value(paramsKey).(map[string]string)
 		fmt.Fprintf(w, "Action: %s", params["action"])
 	})
+
+	if err := r.RunGraceful(":9999"); err != nil {
+		log.Fatal(err)
+	}
 }
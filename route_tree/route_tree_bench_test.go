@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// deepStaticRoutes 函数用于生成 n 条共享前缀、层级较深的静态路由规则
+func deepStaticRoutes(n int) []string {
+	patterns := make([]string, n)
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf("/org%d/projects/settings/members/roles/permissions/item%d", i%10, i)
+	}
+	return patterns
+}
+
+// insertPerSegment 函数用于按未压缩的方式插入静态路由规则，每个路径段对应一个节点，作为基数树压缩前的对照
+func insertPerSegment(n *node, pattern string) {
+	for _, part := range parsePattern(pattern) {
+		var next *node
+		for _, child := range n.children {
+			if child.part == part {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			next = &node{part: part, segs: []string{part}}
+			n.children = append(n.children, next)
+		}
+		n = next
+	}
+	n.pattern = pattern
+}
+
+// BenchmarkDeepStaticSearch 用于比较 1000 条深层静态路由在压缩前后的查找耗时
+func BenchmarkDeepStaticSearch(b *testing.B) {
+	patterns := deepStaticRoutes(1000)
+	compressed, perSegment := &node{}, &node{}
+	for _, pattern := range patterns {
+		compressed.insert(pattern, parsePattern(pattern), 0)
+		insertPerSegment(perSegment, pattern)
+	}
+	lookups := make([][]string, len(patterns))
+	for i, pattern := range patterns {
+		lookups[i] = parsePattern(pattern)
+	}
+
+	for _, bc := range []struct {
+		name string
+		root *node
+	}{
+		{"compressed", compressed},
+		{"per-segment", perSegment},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				parts := lookups[i%len(lookups)]
+				if bc.root.search(parts, parts, 0, false) == nil {
+					b.Fatalf("no match for %v", parts)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetRouteAllocs 用于统计静态路由和带参数路由查找时的内存分配，
+// 带参数路由的参数 map 在每次查找后放回对象池，与 handle 中的用法一致
+func BenchmarkGetRouteAllocs(b *testing.B) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/api/v1/status", text(""))
+	r.addRoute(http.MethodGet, "/api/v1/users/:id", text(""))
+
+	for _, bc := range []struct{ name, path string }{
+		{"static", "/api/v1/status"},
+		{"dynamic", "/api/v1/users/42"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n, params := r.getRoute(http.MethodGet, bc.path)
+				if n == nil {
+					b.Fatal("no match")
+				}
+				putParams(params)
+			}
+		})
+	}
+}
+
+// githubAPI 是 GitHub REST API 的一部分路由，作为基准测试使用的真实路由表
+var githubAPI = []struct{ method, pattern string }{
+	{http.MethodGet, "/authorizations"},
+	{http.MethodGet, "/authorizations/:id"},
+	{http.MethodPost, "/authorizations"},
+	{http.MethodDelete, "/authorizations/:id"},
+	{http.MethodGet, "/applications/:client_id/tokens/:access_token"},
+	{http.MethodGet, "/events"},
+	{http.MethodGet, "/repos/:owner/:repo/events"},
+	{http.MethodGet, "/networks/:owner/:repo/events"},
+	{http.MethodGet, "/orgs/:org/events"},
+	{http.MethodGet, "/users/:user/received_events"},
+	{http.MethodGet, "/users/:user/received_events/public"},
+	{http.MethodGet, "/users/:user/events"},
+	{http.MethodGet, "/users/:user/events/public"},
+	{http.MethodGet, "/users/:user/events/orgs/:org"},
+	{http.MethodGet, "/feeds"},
+	{http.MethodGet, "/notifications"},
+	{http.MethodGet, "/repos/:owner/:repo/notifications"},
+	{http.MethodPut, "/notifications"},
+	{http.MethodGet, "/notifications/threads/:id"},
+	{http.MethodGet, "/notifications/threads/:id/subscription"},
+	{http.MethodGet, "/repos/:owner/:repo/stargazers"},
+	{http.MethodGet, "/users/:user/starred"},
+	{http.MethodGet, "/user/starred"},
+	{http.MethodGet, "/user/starred/:owner/:repo"},
+	{http.MethodGet, "/repos/:owner/:repo/subscribers"},
+	{http.MethodGet, "/users/:user/subscriptions"},
+	{http.MethodGet, "/user/subscriptions"},
+	{http.MethodGet, "/users/:user/gists"},
+	{http.MethodGet, "/gists"},
+	{http.MethodGet, "/gists/public"},
+	{http.MethodGet, "/gists/starred"},
+	{http.MethodGet, "/gists/:id"},
+	{http.MethodPost, "/gists"},
+	{http.MethodGet, "/repos/:owner/:repo/git/blobs/:sha"},
+	{http.MethodGet, "/repos/:owner/:repo/git/commits/:sha"},
+	{http.MethodGet, "/repos/:owner/:repo/git/refs"},
+	{http.MethodGet, "/repos/:owner/:repo/git/refs/*ref"},
+	{http.MethodGet, "/repos/:owner/:repo/git/tags/:sha"},
+	{http.MethodGet, "/repos/:owner/:repo/git/trees/:sha"},
+	{http.MethodGet, "/issues"},
+	{http.MethodGet, "/user/issues"},
+	{http.MethodGet, "/orgs/:org/issues"},
+	{http.MethodGet, "/repos/:owner/:repo/issues"},
+	{http.MethodGet, "/repos/:owner/:repo/issues/:number"},
+	{http.MethodPost, "/repos/:owner/:repo/issues"},
+	{http.MethodGet, "/repos/:owner/:repo/assignees"},
+	{http.MethodGet, "/repos/:owner/:repo/assignees/:assignee"},
+	{http.MethodGet, "/repos/:owner/:repo/issues/:number/comments"},
+	{http.MethodGet, "/repos/:owner/:repo/labels"},
+	{http.MethodGet, "/repos/:owner/:repo/labels/:name"},
+	{http.MethodGet, "/repos/:owner/:repo/milestones"},
+	{http.MethodGet, "/repos/:owner/:repo/milestones/:number"},
+	{http.MethodGet, "/emojis"},
+	{http.MethodGet, "/gitignore/templates"},
+	{http.MethodGet, "/gitignore/templates/:name"},
+	{http.MethodPost, "/markdown"},
+	{http.MethodGet, "/meta"},
+	{http.MethodGet, "/rate_limit"},
+	{http.MethodGet, "/users/:user/orgs"},
+	{http.MethodGet, "/user/orgs"},
+	{http.MethodGet, "/orgs/:org"},
+	{http.MethodGet, "/orgs/:org/members"},
+	{http.MethodGet, "/orgs/:org/members/:user"},
+	{http.MethodGet, "/orgs/:org/teams"},
+	{http.MethodGet, "/teams/:id"},
+	{http.MethodGet, "/teams/:id/members"},
+	{http.MethodGet, "/teams/:id/repos"},
+	{http.MethodGet, "/repos/:owner/:repo/pulls"},
+	{http.MethodGet, "/repos/:owner/:repo/pulls/:number"},
+	{http.MethodGet, "/repos/:owner/:repo/pulls/:number/commits"},
+	{http.MethodGet, "/repos/:owner/:repo/pulls/:number/files"},
+	{http.MethodGet, "/repos/:owner/:repo/pulls/:number/merge"},
+	{http.MethodGet, "/user/repos"},
+	{http.MethodGet, "/users/:user/repos"},
+	{http.MethodGet, "/orgs/:org/repos"},
+	{http.MethodGet, "/repositories"},
+	{http.MethodGet, "/repos/:owner/:repo"},
+	{http.MethodGet, "/repos/:owner/:repo/contributors"},
+	{http.MethodGet, "/repos/:owner/:repo/languages"},
+	{http.MethodGet, "/repos/:owner/:repo/teams"},
+	{http.MethodGet, "/repos/:owner/:repo/tags"},
+	{http.MethodGet, "/repos/:owner/:repo/branches"},
+	{http.MethodGet, "/repos/:owner/:repo/branches/:branch"},
+	{http.MethodGet, "/repos/:owner/:repo/commits"},
+	{http.MethodGet, "/repos/:owner/:repo/commits/:sha"},
+	{http.MethodGet, "/repos/:owner/:repo/readme"},
+	{http.MethodGet, "/repos/:owner/:repo/contents/*path"},
+	{http.MethodGet, "/repos/:owner/:repo/releases"},
+	{http.MethodGet, "/repos/:owner/:repo/releases/:id"},
+	{http.MethodGet, "/repos/:owner/:repo/stats/contributors"},
+	{http.MethodGet, "/search/repositories"},
+	{http.MethodGet, "/search/code"},
+	{http.MethodGet, "/search/issues"},
+	{http.MethodGet, "/search/users"},
+	{http.MethodGet, "/users/:user"},
+	{http.MethodGet, "/user"},
+	{http.MethodGet, "/users"},
+	{http.MethodGet, "/user/emails"},
+	{http.MethodGet, "/users/:user/followers"},
+	{http.MethodGet, "/user/followers"},
+	{http.MethodGet, "/users/:user/following"},
+	{http.MethodGet, "/user/following"},
+	{http.MethodGet, "/user/following/:user"},
+	{http.MethodGet, "/users/:user/following/:target_user"},
+	{http.MethodGet, "/user/keys"},
+	{http.MethodGet, "/user/keys/:id"},
+}
+
+// githubRouter 函数用于创建注册了 githubAPI 中所有路由的路由
+func githubRouter() *router {
+	r := newRouter()
+	for _, route := range githubAPI {
+		r.addRoute(route.method, route.pattern, text(""))
+	}
+	return r
+}
+
+// BenchmarkGetRoute 用于在真实规模的路由表上测量不同类型路由的查找耗时和内存分配。
+// 静态路由的查找不应分配内存，出现分配时基准测试直接失败，作为优化时的底线
+func BenchmarkGetRoute(b *testing.B) {
+	r := githubRouter()
+	for _, bc := range []struct {
+		name, path string
+	}{
+		{"static", "/user/repos"},
+		{"single-param", "/users/gopher"},
+		{"multi-param", "/repos/golang/go/issues/1234/comments"},
+		{"catch-all", "/repos/golang/go/contents/src/net/http/server.go"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			lookup := func() {
+				n, params := r.getRoute(http.MethodGet, bc.path)
+				if n == nil {
+					b.Fatalf("no match for %s", bc.path)
+				}
+				putParams(params)
+			}
+			if bc.name == "static" {
+				if allocs := testing.AllocsPerRun(100, lookup); allocs != 0 {
+					b.Fatalf("static lookup: %v allocs/op, want 0", allocs)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				lookup()
+			}
+		})
+	}
+}
+
+// BenchmarkServeHTTP 用于测量完整分发流程（包括中间件链和上下文注入）在真实路由表上的耗时
+func BenchmarkServeHTTP(b *testing.B) {
+	r := githubRouter()
+	for _, bc := range []struct{ name, path string }{
+		{"static", "/user/repos"},
+		{"multi-param", "/repos/golang/go/issues/1234/comments"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, bc.path, nil)
+			w := httptest.NewRecorder()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+// BenchmarkDeepLookup 用于测量超过 maxStackParts 段的深层路径的查找耗时，
+// 这类路径无法使用栈上的切分缓冲区，覆盖深层静态路由和大量段落入通配符两种情况
+func BenchmarkDeepLookup(b *testing.B) {
+	segments := make([]string, 64)
+	for i := range segments {
+		segments[i] = fmt.Sprintf("s%d", i)
+	}
+	deep := "/" + strings.Join(segments, "/")
+
+	r := newRouter()
+	r.GET(deep, text(""))
+	r.GET("/files/*path", text(""))
+	for _, bc := range []struct{ name, path string }{
+		{"static", deep},
+		{"catch-all", "/files" + deep},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n, params := r.getRoute(http.MethodGet, bc.path)
+				if n == nil {
+					b.Fatalf("no match for %s", bc.path)
+				}
+				putParams(params)
+			}
+		})
+	}
+}
@@ -0,0 +1,1340 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// serve 函数用于向路由发送一个不带请求体的请求并返回记录下来的响应
+func serve(r *router, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+// text 函数用于创建一个只写出固定响应体的处理函数
+func text(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, body)
+	}
+}
+
+// panicMessage 函数用于执行 fn 并返回其 panic 的信息，没有 panic 时返回空字符串
+func panicMessage(fn func()) (msg string) {
+	defer func() {
+		if v := recover(); v != nil {
+			msg = fmt.Sprint(v)
+		}
+	}()
+	fn()
+	return ""
+}
+
+func TestHandleDispatchesDynamicRoute(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/hello", text("static"))
+	r.addRoute(http.MethodGet, "/hello/:name", text("dynamic"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/hello", http.StatusOK, "static"},
+		{"/hello/bob", http.StatusOK, "dynamic"},
+		{"/hello/bob/extra", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, w.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestParamsInjectedIntoContext(t *testing.T) {
+	r := newRouter()
+	var name string
+	r.addRoute(http.MethodGet, "/hello/:name", func(w http.ResponseWriter, req *http.Request) {
+		// 路由参数 map 在处理函数返回后会被放回对象池，因此只在处理函数内读取
+		params := req.Context().Value(paramsKey).(map[string]string)
+		name = params["name"]
+	})
+
+	serve(r, http.MethodGet, "/hello/geektutu")
+	if name != "geektutu" {
+		t.Fatalf(`params["name"] = %q, want "geektutu"`, name)
+	}
+}
+
+func TestCatchAllMatchesRemainingPath(t *testing.T) {
+	r := newRouter()
+	var action string
+	r.addRoute(http.MethodGet, "/user/*action", func(w http.ResponseWriter, req *http.Request) {
+		action = req.Context().Value(paramsKey).(map[string]string)["action"]
+	})
+
+	if w := serve(r, http.MethodGet, "/user/foo/bar"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if action != "foo/bar" {
+		t.Fatalf(`params["action"] = %q, want "foo/bar"`, action)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodPost, "/articles", text("created"))
+	r.addRoute(http.MethodPut, "/articles", text("replaced"))
+
+	w := serve(r, http.MethodGet, "/articles")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /articles: status = %d, want 405", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "POST, PUT" {
+		t.Errorf("Allow = %q, want %q", allow, "POST, PUT")
+	}
+
+	w = serve(r, http.MethodGet, "/missing")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("GET /missing: status = %d, want 404", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "" {
+		t.Errorf("404 response has Allow = %q", allow)
+	}
+}
+
+func TestHeadFallsBackToGet(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/page", text("hello"))
+
+	w := serve(r, http.MethodHead, "/page")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "5" {
+		t.Errorf("Content-Length = %q, want 5", cl)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", w.Body.String())
+	}
+
+	r.HandleHEAD = false
+	if w := serve(r, http.MethodHead, "/page"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("HandleHEAD disabled: status = %d, want 405", w.Code)
+	}
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/items", text("list"))
+	r.addRoute(http.MethodPost, "/items", text("create"))
+
+	w := serve(r, http.MethodOptions, "/items")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", w.Code)
+	}
+	allow := strings.Split(w.Header().Get("Allow"), ", ")
+	sort.Strings(allow)
+	if got, want := strings.Join(allow, ","), "GET,OPTIONS,POST"; got != want {
+		t.Errorf("Allow = %q, want methods %s", w.Header().Get("Allow"), want)
+	}
+
+	// 显式注册的 OPTIONS 路由优先于自动响应
+	r.addRoute(http.MethodOptions, "/items", text("custom"))
+	if w := serve(r, http.MethodOptions, "/items"); w.Code != http.StatusOK || w.Body.String() != "custom" {
+		t.Fatalf("explicit OPTIONS route: status = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	r.HandleOPTIONS = false
+	if w := serve(r, http.MethodOptions, "/missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown path: status = %d, want 404", w.Code)
+	}
+}
+
+func TestReverseURL(t *testing.T) {
+	r := newRouter()
+	r.addRouteNamed(http.MethodGet, "/users/:id/posts/:postID", "post.show", text("post"))
+
+	got, err := r.URL("post.show", map[string]string{"id": "7", "postID": "42"})
+	if err != nil {
+		t.Fatalf("URL: %v", err)
+	}
+	if got != "/users/7/posts/42" {
+		t.Errorf("URL = %q, want /users/7/posts/42", got)
+	}
+
+	if _, err := r.URL("post.show", map[string]string{"id": "7"}); err == nil {
+		t.Error("URL with a missing param returned no error")
+	}
+	if _, err := r.URL("unknown", nil); err == nil {
+		t.Error("URL with an unknown name returned no error")
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	r := newRouter()
+	r.RedirectTrailingSlash = true
+	r.addRoute(http.MethodGet, "/users", text("users"))
+	r.addRoute(http.MethodPost, "/posts/", text("posts"))
+	r.addRoute(http.MethodGet, "/files/*path", text("files"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{http.MethodGet, "/users/", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{http.MethodPost, "/posts", http.StatusPermanentRedirect, "/posts/"},
+		{http.MethodGet, "/users", http.StatusOK, ""},
+		// 通配符路由捕获整个剩余路径，不做重定向
+		{http.MethodGet, "/files/a/", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.path)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: status = %d, Location = %q, want %d, %q",
+				tt.method, tt.path, w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+
+	r.RedirectTrailingSlash = false
+	if w := serve(r, http.MethodGet, "/users/"); w.Code != http.StatusOK {
+		t.Errorf("disabled: status = %d, want 200", w.Code)
+	}
+}
+
+func TestStrictSlash(t *testing.T) {
+	// 关闭时 /users/ 与 /users 是同一条路由，同时注册两者会冲突
+	r := newRouter()
+	r.GET("/users", text("users"))
+	if w := serve(r, http.MethodGet, "/users/"); w.Code != http.StatusOK || w.Body.String() != "users" {
+		t.Errorf("StrictSlash off: GET /users/: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if msg := panicMessage(func() { r.GET("/users/", text("users/")) }); msg == "" {
+		t.Error("StrictSlash off: registering /users/ after /users did not panic")
+	}
+
+	// 开启时两者是不同的路由
+	r = newRouter()
+	r.StrictSlash = true
+	r.GET("/users", text("users"))
+	r.GET("/users/", text("users/"))
+	r.GET("/posts", text("posts"))
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users", http.StatusOK, "users"},
+		{"/users/", http.StatusOK, "users/"},
+		{"/posts/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("StrictSlash on: GET %s: status = %d, body = %q, want %d, %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestStrictSlashRedirect(t *testing.T) {
+	r := newRouter()
+	r.StrictSlash = true
+	r.RedirectTrailingSlash = true
+	r.GET("/users", text("users"))
+	r.POST("/posts/", text("posts"))
+	r.GET("/both", text("both"))
+	r.GET("/both/", text("both/"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{http.MethodGet, "/users/", http.StatusMovedPermanently, "/users"},
+		{http.MethodHead, "/users/", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{http.MethodPost, "/posts", http.StatusPermanentRedirect, "/posts/"},
+		// 两种写法都注册时各自匹配，不做重定向
+		{http.MethodGet, "/both/", http.StatusOK, ""},
+		{http.MethodGet, "/missing/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.path)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: status = %d, Location = %q, want %d, %q",
+				tt.method, tt.path, w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	r := newRouter()
+	r.CaseInsensitive = true
+	var name string
+	r.addRoute(http.MethodGet, "/Users/Profile", text("profile"))
+	r.addRoute(http.MethodGet, "/users/:name/posts", func(w http.ResponseWriter, req *http.Request) {
+		name = req.Context().Value(paramsKey).(map[string]string)["name"]
+	})
+
+	for _, path := range []string{"/users/profile", "/USERS/PROFILE", "/Users/Profile"} {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != "profile" {
+			t.Errorf("GET %s: status = %d, want the profile route", path, w.Code)
+		}
+	}
+
+	serve(r, http.MethodGet, "/USERS/JohnDoe/Posts")
+	if name != "JohnDoe" {
+		t.Errorf(`params["name"] = %q, want original casing "JohnDoe"`, name)
+	}
+}
+
+func TestConflictingRoutesPanic(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/user/:id", text("id"))
+
+	msg := panicMessage(func() { r.addRoute(http.MethodGet, "/user/:name", text("name")) })
+	want := "wildcard ':name' in route '/user/:name' conflicts with wildcard ':id' in existing route '/user/:id'"
+	if msg != want {
+		t.Errorf("conflicting param panic = %q, want %q", msg, want)
+	}
+
+	msg = panicMessage(func() { r.addRoute(http.MethodGet, "/user/:id", text("again")) })
+	if want := "duplicate route registration: GET /user/:id"; msg != want {
+		t.Errorf("duplicate route panic = %q, want %q", msg, want)
+	}
+
+	// 不同方法下的同一路由规则不算冲突
+	if msg := panicMessage(func() { r.addRoute(http.MethodPost, "/user/:id", text("post")) }); msg != "" {
+		t.Errorf("POST /user/:id panicked: %s", msg)
+	}
+}
+
+func TestRegexpConstraints(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, `/users/:id(\d+)`, text("numeric"))
+	r.addRoute(http.MethodGet, `/users/:slug([a-z-]+)`, text("slug"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/42", http.StatusOK, "numeric"},
+		{"/users/abc", http.StatusOK, "slug"},
+		{"/users/john-doe", http.StatusOK, "slug"},
+		{"/users/ABC_1", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s: status = %d, body = %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestRegexpConstraintsCaseInsensitive(t *testing.T) {
+	r := newRouter()
+	r.CaseInsensitive = true
+	var code string
+	r.addRoute(http.MethodGet, "/Codes/:code([A-Z]+)", func(w http.ResponseWriter, req *http.Request) {
+		code = req.Context().Value(paramsKey).(map[string]string)["code"]
+	})
+	upper := func(value string) bool { return value == strings.ToUpper(value) }
+	r.GET("/items/:sku", text("item")).Where("sku", upper)
+
+	// 静态部分忽略大小写，正则约束和 Where 约束作用于参数的原始值
+	if w := serve(r, http.MethodGet, "/codes/ABC"); w.Code != http.StatusOK || code != "ABC" {
+		t.Errorf("GET /codes/ABC: status = %d, code = %q, want 200 ABC", w.Code, code)
+	}
+	if w := serve(r, http.MethodGet, "/CODES/abc"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /CODES/abc: status = %d, want 404", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/ITEMS/BEEF"); w.Code != http.StatusOK {
+		t.Errorf("GET /ITEMS/BEEF: status = %d, want 200", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/items/beef"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /items/beef: status = %d, want 404", w.Code)
+	}
+}
+
+func TestParamsAreUnescaped(t *testing.T) {
+	r := newRouter()
+	var name, file string
+	r.addRoute(http.MethodGet, "/hello/:name", func(w http.ResponseWriter, req *http.Request) {
+		name = req.Context().Value(paramsKey).(map[string]string)["name"]
+	})
+	r.addRoute(http.MethodGet, "/files/*path", func(w http.ResponseWriter, req *http.Request) {
+		file = req.Context().Value(paramsKey).(map[string]string)["path"]
+	})
+
+	serve(r, http.MethodGet, "/hello/John%20Doe")
+	if name != "John Doe" {
+		t.Errorf(`params["name"] = %q, want "John Doe"`, name)
+	}
+	serve(r, http.MethodGet, "/files/my%20docs/a%2Fb.txt")
+	if file != "my docs/a/b.txt" {
+		t.Errorf(`params["path"] = %q, want "my docs/a/b.txt"`, file)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.URL.RawPath = "/hello/John%zzDoe"
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("malformed escape: status = %d, want 400", w.Code)
+	}
+}
+
+// TestConcurrentRegistrationAndServing 需要配合 go test -race 运行，用于检查注册路由与处理请求之间没有数据竞争
+func TestConcurrentRegistrationAndServing(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/ping", text("pong"))
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				r.addRoute(http.MethodGet, fmt.Sprintf("/w%d/r%d/:id", i, j), text("ok"))
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if w := serve(r, http.MethodGet, "/ping"); w.Code != http.StatusOK {
+					t.Errorf("GET /ping: status = %d", w.Code)
+					return
+				}
+				serve(r, http.MethodGet, fmt.Sprintf("/w%d/r%d/1", i, j))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		path := fmt.Sprintf("/w%d/r49/7", i)
+		if w := serve(r, http.MethodGet, path); w.Code != http.StatusOK {
+			t.Errorf("GET %s after registration: status = %d", path, w.Code)
+		}
+	}
+}
+
+func TestMethodHelpers(t *testing.T) {
+	r := newRouter()
+	helpers := map[string]func(string, http.HandlerFunc) *Route{
+		http.MethodGet:    r.GET,
+		http.MethodPost:   r.POST,
+		http.MethodPut:    r.PUT,
+		http.MethodDelete: r.DELETE,
+		http.MethodPatch:  r.PATCH,
+	}
+	for method, register := range helpers {
+		route := register("/res", text(method))
+		if route.Method != method || route.Pattern != "/res" {
+			t.Errorf("%s helper returned %+v", method, route)
+		}
+		if r.handlers[method+"-/res"] == nil {
+			t.Errorf("%s helper did not register under %q", method, method+"-/res")
+		}
+		if w := serve(r, method, "/res"); w.Body.String() != method {
+			t.Errorf("%s /res: body = %q", method, w.Body.String())
+		}
+	}
+
+	// 小写的方法名统一转换为大写
+	r.addRoute("get", "/lower", text("lower"))
+	if r.handlers["GET-/lower"] == nil {
+		t.Error(`addRoute("get") did not register under GET`)
+	}
+}
+
+func TestRoutesListing(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodPost, "/users", text(""))
+	r.addRoute(http.MethodGet, "/users/:id", text(""))
+	r.addRouteNamed(http.MethodGet, "/users", "user.index", text(""))
+	r.addRoute(http.MethodDelete, "/users/:id", text(""))
+
+	want := []RouteInfo{
+		{Method: http.MethodDelete, Pattern: "/users/:id"},
+		{Method: http.MethodGet, Pattern: "/users", Name: "user.index"},
+		{Method: http.MethodGet, Pattern: "/users/:id"},
+		{Method: http.MethodPost, Pattern: "/users"},
+	}
+	if got := r.Routes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Routes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMount(t *testing.T) {
+	sub := newRouter()
+	var calls []string
+	sub.Use(record(&calls, "sub"))
+	sub.GET("/dashboard", text("dashboard"))
+	sub.GET("/", text("index"))
+
+	r := newRouter()
+	r.Mount("/admin", sub)
+
+	for path, want := range map[string]string{"/admin/dashboard": "dashboard", "/admin": "index"} {
+		if w := serve(r, http.MethodGet, path); w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("GET %s: status = %d, body = %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+	if want := []string{"sub", "sub"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("sub-router middleware calls = %v, want %v", calls, want)
+	}
+
+	// 与已有路由冲突时通过冲突检测报告
+	if msg := panicMessage(func() { r.Mount("/admin", sub) }); !strings.Contains(msg, "duplicate route registration") {
+		t.Errorf("mounting twice: panic = %q, want a duplicate route error", msg)
+	}
+}
+
+func TestStaticChainsAreCompressed(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/api/v1/users/list", text("list"))
+	r.addRoute(http.MethodGet, "/api/v1/users/:id/profile", text("profile"))
+	r.addRoute(http.MethodGet, "/api/v1/orders/recent", text("orders"))
+
+	root := r.roots[http.MethodGet]
+	if len(root.children) != 1 || root.children[0].part != "api/v1" {
+		t.Fatalf("root children = %v, want a single merged api/v1 node", root.children)
+	}
+	if got := len(root.children[0].children); got != 2 {
+		t.Fatalf("api/v1 has %d children, want 2 (orders/recent and users)", got)
+	}
+
+	tests := map[string]string{
+		"/api/v1/users/list":      "list",
+		"/api/v1/users/7/profile": "profile",
+		"/api/v1/orders/recent":   "orders",
+	}
+	for path, want := range tests {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != want {
+			t.Errorf("GET %s: status = %d, body = %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+	if n, params := r.getRoute(http.MethodGet, "/api/v1/users/7/profile"); n == nil || params["id"] != "7" {
+		t.Errorf("getRoute params = %v, want id=7", params)
+	}
+	if w := serve(r, http.MethodGet, "/api/v1/users"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /api/v1/users: status = %d, want 404 for a prefix of a merged node", w.Code)
+	}
+}
+
+func TestParamsPooling(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/status", text(""))
+	r.addRoute(http.MethodGet, "/users/:id", text(""))
+
+	// 静态路由不需要参数 map
+	if n, params := r.getRoute(http.MethodGet, "/status"); n == nil || params != nil {
+		t.Fatalf("static route: node = %v, params = %v, want a match with nil params", n, params)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { r.getRoute(http.MethodGet, "/status") }); allocs != 0 {
+		t.Errorf("static lookup allocates %v times, want 0", allocs)
+	}
+
+	// 放回对象池的参数 map 会被清空，下一次查找不会看到上一次的参数
+	_, params := r.getRoute(http.MethodGet, "/users/1")
+	if params["id"] != "1" {
+		t.Fatalf("params = %v, want id=1", params)
+	}
+	putParams(params)
+	if len(params) != 0 {
+		t.Errorf("params after putParams = %v, want empty", params)
+	}
+	if _, params := r.getRoute(http.MethodGet, "/users/2"); len(params) != 1 || params["id"] != "2" {
+		t.Errorf("reused params = %v, want only id=2", params)
+	}
+}
+
+func TestCustomNotFound(t *testing.T) {
+	r := newRouter()
+	var calls []string
+	r.Use(record(&calls, "global"))
+	r.GET("/exists", text("ok"))
+	r.NotFound(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "custom 404: "+req.URL.Path)
+	}))
+
+	w := serve(r, http.MethodGet, "/missing")
+	if w.Code != http.StatusNotFound || w.Body.String() != "custom 404: /missing" {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	// 自定义的 404 处理器同样经过全局中间件
+	if want := []string{"global"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("middleware calls = %v, want %v", calls, want)
+	}
+}
+
+func TestCustomMethodNotAllowed(t *testing.T) {
+	r := newRouter()
+	r.GET("/items", text("list"))
+	r.POST("/items", text("create"))
+	var allow string
+	r.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		allow = w.Header().Get("Allow")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		io.WriteString(w, "custom 405")
+	}))
+
+	w := serve(r, http.MethodDelete, "/items")
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "custom 405" {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	// 调用自定义处理器之前 Allow 头已经设置好
+	if allow != "GET, POST" || w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("Allow seen by handler = %q, in response = %q, want %q", allow, w.Header().Get("Allow"), "GET, POST")
+	}
+}
+
+func TestRemoveRoute(t *testing.T) {
+	r := newRouter()
+	r.GET("/api/users", text("users"))
+	r.GET("/api/users/:id", text("user"))
+	r.GET("/api/posts", text("posts"))
+
+	if !r.RemoveRoute(http.MethodGet, "/api/users/:id") {
+		t.Fatal("RemoveRoute returned false for a registered route")
+	}
+	if w := serve(r, http.MethodGet, "/api/users/1"); w.Code != http.StatusNotFound {
+		t.Errorf("removed route: status = %d, want 404", w.Code)
+	}
+	// 共享前缀的兄弟路由不受影响
+	for path, want := range map[string]string{"/api/users": "users", "/api/posts": "posts"} {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != want {
+			t.Errorf("GET %s: status = %d, body = %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+
+	if r.RemoveRoute(http.MethodGet, "/api/users/:id") {
+		t.Error("removing the same route twice returned true")
+	}
+	if r.RemoveRoute(http.MethodPost, "/api/users") {
+		t.Error("removing a route under another method returned true")
+	}
+
+	// 只剩一个静态子节点的静态节点与子节点重新合并
+	r.RemoveRoute(http.MethodGet, "/api/posts")
+	if root := r.roots[http.MethodGet]; len(root.children) != 1 || root.children[0].part != "api/users" {
+		t.Errorf("root children = %v, want a single merged api/users node", root.children)
+	}
+
+	// 不再通向任何路由的节点被剪除
+	r.RemoveRoute(http.MethodGet, "/api/users")
+	if root := r.roots[http.MethodGet]; len(root.children) != 0 {
+		t.Errorf("root still has children %v after removing every route", root.children)
+	}
+}
+
+func TestStaticBeatsWildcardRegardlessOfOrder(t *testing.T) {
+	orders := [][]string{
+		{"/user/:id", "/user/me"},
+		{"/user/me", "/user/:id"},
+	}
+	for _, patterns := range orders {
+		r := newRouter()
+		for _, pattern := range patterns {
+			r.GET(pattern, text(pattern))
+		}
+		if w := serve(r, http.MethodGet, "/user/me"); w.Body.String() != "/user/me" {
+			t.Errorf("registered %v: GET /user/me hit %q, want the static route", patterns, w.Body.String())
+		}
+		if w := serve(r, http.MethodGet, "/user/42"); w.Body.String() != "/user/:id" {
+			t.Errorf("registered %v: GET /user/42 hit %q, want the param route", patterns, w.Body.String())
+		}
+	}
+}
+
+func TestSearchBacktracksToWildcards(t *testing.T) {
+	r := newRouter()
+	r.GET("/static/index.html", text("index"))
+	r.GET("/static/:file", text("file"))
+	r.GET("/a/b/c", text("static chain"))
+	r.GET("/a/:x/d", text("one level"))
+	r.GET("/:y/b/e", text("two levels"))
+
+	tests := map[string]string{
+		"/static/index.html": "index",
+		// 静态子节点 index.html 不匹配时回退到参数子节点
+		"/static/other.txt": "file",
+		"/a/b/c":            "static chain",
+		// 静态分支 a/b 在下一层走不通，回溯到 a 下的参数节点
+		"/a/b/d": "one level",
+		// 回溯两层，直到根节点下的参数节点
+		"/a/b/e": "two levels",
+	}
+	for path, want := range tests {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != want {
+			t.Errorf("GET %s: status = %d, body = %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"/:", "route '/:': invalid param name in segment ':'"},
+		{"/*", "route '/*': invalid catch-all name in segment '*'"},
+		{"/files/*path/more", "route '/files/*path/more': catch-all '*path' must be the last segment"},
+		{"/a:b", "route '/a:b': ':' and '*' are only allowed at the start of a segment 'a:b' (use \\: or \\* for literals)"},
+		{"/:0\x9e:0", `route "/:0\x9e:0": pattern is not valid UTF-8`},
+	}
+	for _, tt := range tests {
+		r := newRouter()
+		err := r.AddRouteE(http.MethodGet, tt.pattern, text("x"))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("AddRouteE(%q) = %v, want %q", tt.pattern, err, tt.want)
+		}
+		if msg := panicMessage(func() { r.GET(tt.pattern, text("x")) }); msg != tt.want {
+			t.Errorf("GET(%q) panic = %q, want %q", tt.pattern, msg, tt.want)
+		}
+	}
+}
+
+// FuzzAddRoute 用随机的路由规则调用 AddRouteE，非法的规则只能返回错误，不能 panic；
+// 注册成功后用规则本身作为请求路径查找，同样不能 panic
+func FuzzAddRoute(f *testing.F) {
+	seeds := []string{
+		"/", "/:", "/*", "/:name", "/*filepath", "/a/*x/b", "/a:b", "/a*b",
+		"/user/:id([0-9]+)", "/files/:name.:ext", "/posts/:slug?", "/{id}", "//", "",
+		"/:0\x9e:0",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, pattern string) {
+		r := newRouter()
+		r.GET("/static/path", text("static"))
+		r.GET("/user/:id", text("user"))
+		defer func() {
+			if v := recover(); v != nil {
+				t.Fatalf("pattern %q: panic: %v", pattern, v)
+			}
+		}()
+		if err := r.AddRouteE(http.MethodGet, pattern, text("fuzz")); err != nil {
+			return
+		}
+		r.getRoute(http.MethodGet, pattern)
+	})
+}
+
+func TestMultipleParamsInSegment(t *testing.T) {
+	r := newRouter()
+	r.GET("/files/:name.:ext", func(w http.ResponseWriter, req *http.Request) {
+		c := newContext(w, req)
+		fmt.Fprintf(w, "name=%s ext=%s", c.Param("name"), c.Param("ext"))
+	})
+
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{"/files/report.pdf", http.StatusOK, "name=report ext=pdf"},
+		{"/files/archive.tar.gz", http.StatusOK, "name=archive ext=tar.gz"},
+		{"/files/report", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.target)
+		if w.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.target, w.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q, want %q", tt.target, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestMatchedPattern(t *testing.T) {
+	r := newRouter()
+	var fromReq, fromCtx string
+	r.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			next(w, req)
+			fromReq = MatchedPattern(req)
+		}
+	})
+	r.addRouteCtx(http.MethodGet, "/users/:id", func(c *Context) {
+		fromCtx = c.MatchedRoute()
+	})
+
+	serve(r, http.MethodGet, "/users/7")
+	if fromCtx != "/users/:id" {
+		t.Errorf("MatchedRoute() = %q, want %q", fromCtx, "/users/:id")
+	}
+	if fromReq != "/users/:id" {
+		t.Errorf("MatchedPattern(req) in middleware = %q, want %q", fromReq, "/users/:id")
+	}
+
+	serve(r, http.MethodGet, "/missing")
+	if fromReq != "" {
+		t.Errorf("MatchedPattern(req) for unmatched request = %q, want empty", fromReq)
+	}
+}
+
+func TestCatchAllIsLastResort(t *testing.T) {
+	orders := [][]string{
+		{"/files/*path", "/files/meta/:id"},
+		{"/files/meta/:id", "/files/*path"},
+	}
+	for _, patterns := range orders {
+		r := newRouter()
+		for _, pattern := range patterns {
+			r.GET(pattern, text(pattern))
+		}
+		tests := map[string]string{
+			"/files/meta/5": "/files/meta/:id",
+			"/files/a/b/c":  "/files/*path",
+			// meta 分支在下一层走不通时回退到通配符
+			"/files/meta/5/raw": "/files/*path",
+			"/files/meta":       "/files/*path",
+		}
+		for path, want := range tests {
+			if w := serve(r, http.MethodGet, path); w.Body.String() != want {
+				t.Errorf("registered %v: GET %s: status = %d, body = %q, want %q", patterns, path, w.Code, w.Body.String(), want)
+			}
+		}
+	}
+}
+
+func TestANY(t *testing.T) {
+	r := newRouter()
+	r.ANY("/echo", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "any %s", req.Method)
+	})
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPatch} {
+		if w := serve(r, method, "/echo"); w.Code != http.StatusOK || w.Body.String() != "any "+method {
+			t.Errorf("%s /echo: status = %d, body = %q", method, w.Code, w.Body.String())
+		}
+	}
+
+	// 之后显式注册的方法覆盖 ANY 注册的处理函数，其他方法不受影响
+	r.POST("/echo", text("explicit post"))
+	if w := serve(r, http.MethodPost, "/echo"); w.Body.String() != "explicit post" {
+		t.Errorf("POST /echo after override: body = %q", w.Body.String())
+	}
+	if w := serve(r, http.MethodGet, "/echo"); w.Body.String() != "any GET" {
+		t.Errorf("GET /echo after override: body = %q", w.Body.String())
+	}
+}
+
+func TestAddRouteE(t *testing.T) {
+	r := newRouter()
+	if err := r.AddRouteE(http.MethodGet, "/users/:id", text("user")); err != nil {
+		t.Fatalf("AddRouteE: %v", err)
+	}
+	if w := serve(r, http.MethodGet, "/users/1"); w.Body.String() != "user" {
+		t.Fatalf("GET /users/1: body = %q", w.Body.String())
+	}
+
+	before := r.DumpTree(http.MethodGet)
+	routes := len(r.Routes())
+	failures := []string{
+		"/users/:name/posts", // 与 :id 冲突
+		"/users/:id",         // 重复注册
+		"/files/*path/x",     // 语法错误
+	}
+	for _, pattern := range failures {
+		if err := r.AddRouteE(http.MethodGet, pattern, text("bad")); err == nil {
+			t.Errorf("AddRouteE(%q): want error", pattern)
+		}
+	}
+	if after := r.DumpTree(http.MethodGet); after != before {
+		t.Errorf("tree changed after failed adds:\n%s\nwant\n%s", after, before)
+	}
+	if got := len(r.Routes()); got != routes {
+		t.Errorf("len(Routes()) = %d after failed adds, want %d", got, routes)
+	}
+	if w := serve(r, http.MethodGet, "/users/1"); w.Body.String() != "user" {
+		t.Errorf("GET /users/1 after failed adds: body = %q", w.Body.String())
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	r := newRouter()
+	r.GET("/hello/:name", text("hello"))
+	r.GET("/a/b", text("ab"))
+
+	// 关闭时（默认）不规范的路径直接返回 400，不会被当作规范路径匹配
+	for _, path := range []string{"/hello//bob", "/a/./b", "/a/x/../b"} {
+		if w := serve(r, http.MethodGet, path); w.Code != http.StatusBadRequest {
+			t.Errorf("CleanPath off: GET %s: status = %d, want 400", path, w.Code)
+		}
+	}
+
+	r.CleanPath = true
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{http.MethodGet, "/hello//bob", http.StatusMovedPermanently, "/hello/bob"},
+		{http.MethodGet, "/a/./b", http.StatusMovedPermanently, "/a/b"},
+		{http.MethodGet, "/a/x/../b?q=1", http.StatusMovedPermanently, "/a/b?q=1"},
+		{http.MethodPost, "/a//b", http.StatusPermanentRedirect, "/a/b"},
+		{http.MethodGet, "/hello/bob", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.path)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("CleanPath on: %s %s: status = %d, Location = %q, want %d, %q",
+				tt.method, tt.path, w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+}
+
+func TestOptionalSegments(t *testing.T) {
+	r := newRouter()
+	r.GET("/posts/:year/:month?", func(w http.ResponseWriter, req *http.Request) {
+		c := newContext(w, req)
+		month, ok := c.Params["month"]
+		fmt.Fprintf(w, "year=%s month=%q set=%v", c.Param("year"), month, ok)
+	})
+
+	tests := map[string]string{
+		"/posts/2024/03": `year=2024 month="03" set=true`,
+		// 缺省的可选参数同样出现在参数中，值为空字符串
+		"/posts/2024": `year=2024 month="" set=true`,
+	}
+	for path, want := range tests {
+		if w := serve(r, http.MethodGet, path); w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("GET %s: status = %d, body = %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+	if w := serve(r, http.MethodGet, "/posts"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /posts: status = %d, want 404", w.Code)
+	}
+}
+
+func TestRegistrationOrderIndependence(t *testing.T) {
+	patterns := []string{
+		"/",
+		"/users",
+		"/users/new",
+		"/users/:id",
+		"/users/:id/edit",
+		"/files/*path",
+		"/files/meta/:id",
+		"/assets/:name.:ext",
+		"/static/index.html",
+		"/static/:file",
+		"/:lang/docs",
+	}
+	probes := []string{
+		"/", "/users", "/users/new", "/users/42", "/users/42/edit", "/users/new/edit",
+		"/files/a/b", "/files/meta/1", "/files/meta/1/x", "/assets/app.js",
+		"/static/index.html", "/static/app.css", "/en/docs", "/users/docs", "/nope/nope",
+	}
+
+	// resolve 函数用于按 order 注册路由，返回每个探测路径匹配到的路由规则
+	resolve := func(order []string) map[string]string {
+		r := newRouter()
+		for _, pattern := range order {
+			r.GET(pattern, text(pattern))
+		}
+		got := make(map[string]string, len(probes))
+		for _, path := range probes {
+			w := serve(r, http.MethodGet, path)
+			got[path] = fmt.Sprintf("%d %s", w.Code, w.Body.String())
+		}
+		return got
+	}
+
+	reversed := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		reversed[len(patterns)-1-i] = pattern
+	}
+	sorted := append([]string(nil), patterns...)
+	sort.Strings(sorted)
+
+	want := resolve(patterns)
+	for _, order := range [][]string{reversed, sorted} {
+		got := resolve(order)
+		for _, path := range probes {
+			if got[path] != want[path] {
+				t.Errorf("registered %v: GET %s = %q, want %q", order, path, got[path], want[path])
+			}
+		}
+	}
+
+	// 抽查几条优先级：静态优先于参数，参数优先于通配符
+	for path, pattern := range map[string]string{
+		"/users/new":      "/users/new",
+		"/users/42":       "/users/:id",
+		"/files/meta/1":   "/files/meta/:id",
+		"/files/a/b":      "/files/*path",
+		"/users/docs":     "/users/:id",
+		"/en/docs":        "/:lang/docs",
+		"/static/app.css": "/static/:file",
+	} {
+		if got := want[path]; got != "200 "+pattern {
+			t.Errorf("GET %s = %q, want 200 %s", path, got, pattern)
+		}
+	}
+}
+
+func TestEscapedLiterals(t *testing.T) {
+	r := newRouter()
+	r.GET(`/config/\:section`, func(w http.ResponseWriter, req *http.Request) {
+		c := newContext(w, req)
+		fmt.Fprintf(w, "literal params=%d", len(c.Params))
+	})
+	r.GET(`/time\:now`, text("time:now"))
+	r.GET(`/glob/\*`, text("star"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/config/:section", http.StatusOK, "literal params=0"},
+		// 转义后的 : 是普通字符，不会捕获参数
+		{"/config/general", http.StatusNotFound, ""},
+		{"/time:now", http.StatusOK, "time:now"},
+		{"/glob/*", http.StatusOK, "star"},
+		{"/glob/anything", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s: status = %d, body = %q, want %d, %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestHandleAndHandlePrefix(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "legacy users %s", req.URL.Path)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "legacy root %s", req.URL.Path)
+	})
+
+	r := newRouter()
+	r.HandlePrefix("/legacy", mux)
+	r.Handle("get", "/health", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "healthy")
+	}))
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/legacy/users", "legacy users /users"},
+		{http.MethodPost, "/legacy/users", "legacy users /users"},
+		{http.MethodGet, "/legacy", "legacy root /"},
+		{http.MethodGet, "/legacy/other/page", "legacy root /other/page"},
+		{http.MethodGet, "/health", "healthy"},
+	}
+	for _, tt := range tests {
+		if w := serve(r, tt.method, tt.path); w.Body.String() != tt.body {
+			t.Errorf("%s %s: status = %d, body = %q, want %q", tt.method, tt.path, w.Code, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestFallbackErrorsNegotiateJSON(t *testing.T) {
+	r := newRouter()
+	r.GET("/items", text("list"))
+
+	tests := []struct {
+		method, path, accept string
+		code                 int
+		contentType, body    string
+	}{
+		{http.MethodGet, "/missing", "application/json", http.StatusNotFound,
+			"application/json", `{"error":"not found","path":"/missing"}` + "\n"},
+		{http.MethodDelete, "/items", "application/json", http.StatusMethodNotAllowed,
+			"application/json", `{"error":"method not allowed","path":"/items"}` + "\n"},
+		{http.MethodGet, "/missing", "text/plain", http.StatusNotFound,
+			"text/plain; charset=utf-8", "404 page not found\n"},
+		{http.MethodGet, "/missing", "text/html,*/*;q=0.8", http.StatusNotFound,
+			"text/plain; charset=utf-8", "404 page not found\n"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%s %s (Accept: %s): status = %d, Content-Type = %q, body = %q, want %d, %q, %q",
+				tt.method, tt.path, tt.accept, w.Code, w.Header().Get("Content-Type"), w.Body.String(), tt.code, tt.contentType, tt.body)
+		}
+	}
+}
+
+func TestRouteMethodValidation(t *testing.T) {
+	r := newRouter()
+	r.addRoute("get", "/users", text("users"))
+	if w := serve(r, http.MethodGet, "/users"); w.Code != http.StatusOK || w.Body.String() != "users" {
+		t.Errorf("lowercase get: GET /users: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if routes := r.Routes(); len(routes) != 1 || routes[0].Method != http.MethodGet {
+		t.Errorf("Routes() = %v, want one GET route", routes)
+	}
+
+	tests := []struct {
+		method, want string
+	}{
+		{"", "route method must not be empty"},
+		{"GTE", `unknown route method "GTE" (set AllowCustomMethods to register it)`},
+		{"PROPFIND", `unknown route method "PROPFIND" (set AllowCustomMethods to register it)`},
+	}
+	for _, tt := range tests {
+		if msg := panicMessage(func() { r.addRoute(tt.method, "/x", text("x")) }); msg != tt.want {
+			t.Errorf("addRoute(%q) panic = %q, want %q", tt.method, msg, tt.want)
+		}
+	}
+
+	r.AllowCustomMethods = true
+	r.addRoute("PROPFIND", "/dav", text("dav"))
+	if w := serve(r, "PROPFIND", "/dav"); w.Body.String() != "dav" {
+		t.Errorf("custom method: body = %q", w.Body.String())
+	}
+	if err := r.AddRouteE("BAD METHOD", "/x", text("x")); err == nil || err.Error() != `invalid route method "BAD METHOD"` {
+		t.Errorf("AddRouteE with a non-token method: error = %v", err)
+	}
+}
+
+func TestFixPath(t *testing.T) {
+	r := newRouter()
+	r.FixPath = true
+	r.GET("/users", text("users"))
+	r.GET("/users/:name/Profile", text("profile"))
+	r.POST("/items", text("items"))
+	// 只有大小写不同的两条路由，修正结果不唯一
+	r.GET("/Docs", text("Docs"))
+	r.GET("/docs", text("docs"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{http.MethodGet, "/Users", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/USERS?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		// 参数值保留原样，只修正静态部分
+		{http.MethodGet, "/USERS/Bob/profile", http.StatusMovedPermanently, "/users/Bob/Profile"},
+		{http.MethodPost, "/ITEMS", http.StatusPermanentRedirect, "/items"},
+		// 开启 HandleHEAD 时 HEAD 请求修正到 GET 路由
+		{http.MethodHead, "/Users", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/DOCS", http.StatusNotFound, ""},
+		{http.MethodGet, "/users", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.path)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: status = %d, Location = %q, want %d, %q",
+				tt.method, tt.path, w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+
+	r.HandleHEAD = false
+	if w := serve(r, http.MethodHead, "/Users"); w.Code != http.StatusNotFound {
+		t.Errorf("HandleHEAD off: HEAD /Users: status = %d, want 404", w.Code)
+	}
+
+	r.FixPath = false
+	if w := serve(r, http.MethodGet, "/Users"); w.Code != http.StatusNotFound {
+		t.Errorf("FixPath off: GET /Users: status = %d, want 404", w.Code)
+	}
+}
+
+func TestGETMany(t *testing.T) {
+	r := newRouter()
+	calls := 0
+	r.GETMany([]string{"/", "/index", "/home"}, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		io.WriteString(w, "home")
+	})
+	for _, path := range []string{"/", "/index", "/home"} {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != "home" {
+			t.Errorf("GET %s: status = %d, body = %q", path, w.Code, w.Body.String())
+		}
+	}
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+
+	// 某个规则冲突时报告错误，并撤销同一批中已经注册的规则
+	r.GET("/users/:id", text("user"))
+	msg := panicMessage(func() {
+		r.POSTMany([]string{"/a", "/b"}, text("ab"))
+		r.GETMany([]string{"/about", "/users/:name"}, text("about"))
+	})
+	if want := "wildcard ':name' in route '/users/:name' conflicts with wildcard ':id' in existing route '/users/:id'"; msg != want {
+		t.Errorf("panic = %q, want %q", msg, want)
+	}
+	if w := serve(r, http.MethodGet, "/about"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /about after a failed batch: status = %d, want 404", w.Code)
+	}
+	if w := serve(r, http.MethodPost, "/b"); w.Body.String() != "ab" {
+		t.Errorf("POST /b from an earlier batch: body = %q", w.Body.String())
+	}
+
+	// 撤销时只恢复被覆盖的 ANY 路由，不删除调用之前已经存在的路由
+	r.ANY("/c", text("any c"))
+	panicMessage(func() { r.GETMany([]string{"/c", "/users/:name"}, text("get c")) })
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if w := serve(r, method, "/c"); w.Code != http.StatusOK || w.Body.String() != "any c" {
+			t.Errorf("%s /c after a failed batch: status = %d, body = %q, want the ANY route", method, w.Code, w.Body.String())
+		}
+	}
+	// 恢复后的路由仍然可以被显式注册覆盖
+	r.GET("/c", text("get c"))
+	if w := serve(r, http.MethodGet, "/c"); w.Body.String() != "get c" {
+		t.Errorf("GET /c after overriding the restored ANY route: body = %q", w.Body.String())
+	}
+}
+
+func TestCatchAllSlash(t *testing.T) {
+	for _, tt := range []struct {
+		slash bool
+		want  map[string]string
+		// 约束看到的值与处理函数收到的值相同，ParamMaxLen(3) 对两种形式的截断位置不同
+		limited map[string]int
+	}{
+		{false, map[string]string{
+			"/files/foo/bar": "foo/bar",
+			"/files/a%2Fb/c": "a/b/c",
+			"/files/a":       "a",
+		}, map[string]int{
+			"/short/abc":  http.StatusOK,
+			"/short/abcd": http.StatusNotFound,
+		}},
+		{true, map[string]string{
+			"/files/foo/bar": "/foo/bar",
+			"/files/a%2Fb/c": "/a/b/c",
+			"/files/a":       "/a",
+		}, map[string]int{
+			"/short/ab":  http.StatusOK,
+			"/short/abc": http.StatusNotFound,
+		}},
+	} {
+		r := newRouter()
+		r.CatchAllSlash = tt.slash
+		r.addRouteNamed(http.MethodGet, "/files/*path", "files", func(w http.ResponseWriter, req *http.Request) {
+			io.WriteString(w, newContext(w, req).Param("path"))
+		})
+		r.GET("/short/*path", text("short")).Where("path", ParamMaxLen(3))
+		for path, want := range tt.want {
+			if w := serve(r, http.MethodGet, path); w.Body.String() != want {
+				t.Errorf("CatchAllSlash=%v: GET %s: status = %d, path = %q, want %q", tt.slash, path, w.Code, w.Body.String(), want)
+			}
+		}
+		for path, want := range tt.limited {
+			if w := serve(r, http.MethodGet, path); w.Code != want {
+				t.Errorf("CatchAllSlash=%v: GET %s: status = %d, want %d", tt.slash, path, w.Code, want)
+			}
+		}
+
+		// 两种形式的值都能用于生成 URL，不会出现 //
+		for _, value := range []string{"a/b", "/a/b"} {
+			if got, err := r.URL("files", map[string]string{"path": value}); err != nil || got != "/files/a/b" {
+				t.Errorf("CatchAllSlash=%v: URL(path=%q) = %q, %v, want /files/a/b", tt.slash, value, got, err)
+			}
+		}
+	}
+}
+
+func TestMaxPathDepth(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		r := newRouter()
+		r.StrictSlash = strict
+		r.MaxPathDepth = 2
+		r.GET("/*path", text("ok"))
+
+		// 段数按非空路径段计算，末尾的斜杠不计入
+		for path, want := range map[string]int{
+			"/a":      http.StatusOK,
+			"/a/b":    http.StatusOK,
+			"/a/b/":   http.StatusOK,
+			"/a/b/c":  http.StatusBadRequest,
+			"/a/b/c/": http.StatusBadRequest,
+		} {
+			if w := serve(r, http.MethodGet, path); w.Code != want {
+				t.Errorf("StrictSlash=%v: GET %s: status = %d, want %d", strict, path, w.Code, want)
+			}
+		}
+	}
+}
+
+func TestDeepStaticRoute(t *testing.T) {
+	segments := make([]string, 300)
+	for i := range segments {
+		segments[i] = fmt.Sprintf("s%d", i)
+	}
+	deep := "/" + strings.Join(segments, "/")
+
+	r := newRouter()
+	r.GET(deep, text("deep"))
+	r.GET(deep+"/:id", text("deep param"))
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		path     string
+		code     int
+		body     string
+	}{
+		{"static", 0, deep, http.StatusOK, "deep"},
+		{"param", 0, deep + "/42", http.StatusOK, "deep param"},
+		{"sibling", 0, deep + "x", http.StatusNotFound, ""},
+		// 限制恰好等于段数时允许，少一段时拒绝
+		{"at limit", 300, deep, http.StatusOK, "deep"},
+		{"at limit with slash", 300, deep + "/", http.StatusOK, "deep"},
+		{"over limit", 299, deep, http.StatusBadRequest, ""},
+		{"param over limit", 300, deep + "/42", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		r.MaxPathDepth = tt.maxDepth
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: GET <%d segments>: status = %d, body = %q, want %d, %q",
+				tt.name, pathDepth(tt.path), w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestDeepCatchAll(t *testing.T) {
+	segments := make([]string, 300)
+	for i := range segments {
+		segments[i] = fmt.Sprintf("s%d", i)
+	}
+	rest := strings.Join(segments, "/")
+
+	r := newRouter()
+	r.GET("/files/*path", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, newContext(w, req).Param("path"))
+	})
+	w := serve(r, http.MethodGet, "/files/"+rest)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /files/<300 segments>: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w.Body.String() != rest {
+		t.Errorf("GET /files/<300 segments>: path has %d segments, want 300", strings.Count(w.Body.String(), "/")+1)
+	}
+}
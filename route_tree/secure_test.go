@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	r := newRouter()
+	r.Use(SecureHeaders(SecureHeadersOptions{}))
+	r.GET("/", text("ok"))
+
+	w := serve(r, http.MethodGet, "/")
+	defaults := map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "SAMEORIGIN",
+		"Content-Security-Policy": "default-src 'self'",
+		"Referrer-Policy":         "strict-origin-when-cross-origin",
+	}
+	for key, want := range defaults {
+		if got := w.Header().Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("plaintext request: Strict-Transport-Security = %q, want unset", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("TLS request: Strict-Transport-Security = %q", got)
+	}
+}
+
+func TestSecureHeadersOptions(t *testing.T) {
+	r := newRouter()
+	r.Use(SecureHeaders(SecureHeadersOptions{
+		FrameOptions:          "DENY",
+		ContentSecurityPolicy: "-",
+	}))
+	r.GET("/", text("ok"))
+
+	w := serve(r, http.MethodGet, "/")
+	if got := w.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Errorf("X-Frame-Options = %q, want DENY", got)
+	}
+	if _, ok := w.Header()["Content-Security-Policy"]; ok {
+		t.Error("Content-Security-Policy was sent although it was omitted")
+	}
+}
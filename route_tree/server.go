@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout 是 RunGraceful 收到退出信号后等待处理中的请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
+// SetDefaultTimeout 方法用于为每个匹配到路由的请求的 context 设置超时时间，路由可以通过 Route.WithTimeout 单独覆盖。
+// 与 Timeout 中间件不同，它不会中断处理函数，只是设置截止时间，处理函数和使用该 context 的下游调用
+// （例如 http.NewRequestWithContext 发出的请求）可以据此提前结束。d 为 0 时不设置超时
+func (r *router) SetDefaultTimeout(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultTimeout = d
+}
+
+// Run 方法用于在 addr 上启动 HTTP 服务，直到服务出错或被 Shutdown 关闭。
+// 通过 Shutdown 正常关闭时返回 nil
+func (r *router) Run(addr string) error {
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return r.Serve(ln)
+}
+
+// Serve 方法与 Run 相同，但在已有的 ln 上接受连接，例如在 127.0.0.1:0 上监听以使用随机端口。
+// Serve 返回时 ln 已被关闭
+func (r *router) Serve(ln net.Listener) error {
+	if err := r.httpServer().Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 方法用于优雅地关闭由 Run 启动的服务：停止接受新连接，并等待处理中的请求完成或 ctx 结束。
+// 在 Run 之前调用时，之后的 Run 和 Serve 会立即返回 nil
+func (r *router) Shutdown(ctx context.Context) error {
+	return r.httpServer().Shutdown(ctx)
+}
+
+// httpServer 方法用于获取 Run、Serve 与 Shutdown 共用的 http.Server，第一次调用时创建，
+// 这样先于服务启动调用的 Shutdown 也能关闭之后启动的服务
+func (r *router) httpServer() *http.Server {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.server == nil {
+		r.server = &http.Server{Handler: r}
+	}
+	return r.server
+}
+
+// RunGraceful 方法与 Run 相同，但会监听 SIGINT 和 SIGTERM 信号，收到信号后优雅地关闭服务
+func (r *router) RunGraceful(addr string) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.Run(addr)
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-quit:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		return err
+	}
+	return <-errCh
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServeAndShutdown(t *testing.T) {
+	r := newRouter()
+	r.GET("/ping", text("pong"))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.Serve(ln)
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Fatalf("GET /ping: status = %d, body = %q", resp.StatusCode, body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Serve() = %v, want nil", err)
+	}
+}
+
+func TestShutdownBeforeServe(t *testing.T) {
+	r := newRouter()
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.Serve(ln)
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Serve() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve kept running after Shutdown")
+	}
+}
+
+func TestRouterIsHTTPHandler(t *testing.T) {
+	r := newRouter()
+	r.GET("/hello/:name", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("hello " + newContext(w, req).Param("name")))
+	})
+
+	var _ http.Handler = r
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/hello/gopher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hello gopher" {
+		t.Errorf("status = %d, body = %q", resp.StatusCode, body)
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	r := newRouter()
+	r.SetDefaultTimeout(2 * time.Second)
+	remaining := make(map[string]time.Duration)
+	record := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if deadline, ok := req.Context().Deadline(); ok {
+				remaining[name] = time.Until(deadline)
+			}
+		}
+	}
+	r.GET("/default", record("default"))
+	r.GET("/slow", record("slow")).WithTimeout(time.Minute)
+
+	serve(r, http.MethodGet, "/default")
+	serve(r, http.MethodGet, "/slow")
+
+	tests := []struct {
+		name string
+		want time.Duration
+	}{
+		{"default", 2 * time.Second},
+		{"slow", time.Minute},
+	}
+	for _, tt := range tests {
+		got, ok := remaining[tt.name]
+		if !ok {
+			t.Errorf("%s: context has no deadline", tt.name)
+			continue
+		}
+		if got > tt.want || got < tt.want-time.Second {
+			t.Errorf("%s: deadline in %v, want about %v", tt.name, got, tt.want)
+		}
+	}
+
+	r.SetDefaultTimeout(0)
+	delete(remaining, "default")
+	serve(r, http.MethodGet, "/default")
+	if _, ok := remaining["default"]; ok {
+		t.Error("SetDefaultTimeout(0): context still has a deadline")
+	}
+}
+
+func TestWithTimeoutIsNotMeta(t *testing.T) {
+	r := newRouter()
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		_, deadline := req.Context().Deadline()
+		value, ok := RouteMeta(req, "timeout")
+		fmt.Fprintf(w, "deadline=%v meta=%v,%v", deadline, value, ok)
+	}
+	// 用户自己的 "timeout" 元数据不会设置超时，WithTimeout 也不会出现在元数据中
+	r.GET("/meta", handler).WithMeta("timeout", time.Second)
+	r.GET("/timeout", handler).WithTimeout(time.Minute)
+
+	for path, want := range map[string]string{
+		"/meta":    "deadline=false meta=1s,true",
+		"/timeout": "deadline=true meta=<nil>,false",
+	} {
+		if w := serve(r, http.MethodGet, path); w.Body.String() != want {
+			t.Errorf("GET %s: body = %q, want %q", path, w.Body.String(), want)
+		}
+	}
+}
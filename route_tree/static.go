@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Static 方法用于将 rootDir 目录下的文件映射到 urlPrefix 前缀下，
+// 例如 r.Static("/assets", "./public") 会注册 /assets/*filepath 路由
+func (r *router) Static(urlPrefix, rootDir string) {
+	pattern := path.Join(urlPrefix, "/*filepath")
+	r.addRoute(http.MethodGet, pattern, func(w http.ResponseWriter, req *http.Request) {
+		params := req.Context().Value(paramsKey).(map[string]string)
+
+		file, ok := resolveStaticPath(rootDir, strings.TrimPrefix(params["filepath"], "/"))
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		if info.IsDir() {
+			http.ServeFile(w, req, file)
+			return
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		defer f.Close()
+
+		// 设置 ETag 后，http.ServeContent 会根据 If-None-Match 和 If-Modified-Since 返回 304，
+		// 并处理 Range 请求，返回 206 或 416
+		w.Header().Set("ETag", fileETag(info))
+		http.ServeContent(w, req, info.Name(), info.ModTime(), f)
+	})
+}
+
+// File 方法用于为 pattern 注册一个只返回单个文件的 GET 路由，例如 r.File("/favicon.ico", "./public/favicon.ico")。
+// 文件在请求时读取，不存在或是目录时返回 404
+func (r *router) File(pattern, file string) {
+	r.addRoute(http.MethodGet, pattern, func(w http.ResponseWriter, req *http.Request) {
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
+			http.NotFound(w, req)
+			return
+		}
+		http.ServeFile(w, req, file)
+	})
+}
+
+// StaticFS 方法用于将 fsys 中的文件映射到 urlPrefix 前缀下，适合配合 //go:embed 打包的静态资源使用。
+// Content-Type 根据文件扩展名确定，文件不存在或是目录时返回 404
+func (r *router) StaticFS(urlPrefix string, fsys fs.FS) {
+	pattern := path.Join(urlPrefix, "/*filepath")
+	r.addRoute(http.MethodGet, pattern, func(w http.ResponseWriter, req *http.Request) {
+		params := req.Context().Value(paramsKey).(map[string]string)
+
+		name := path.Clean(strings.TrimPrefix(params["filepath"], "/"))
+		if !fs.ValidPath(name) {
+			http.NotFound(w, req)
+			return
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, req)
+			return
+		}
+
+		// http.ServeContent 需要 io.ReadSeeker，不支持 Seek 的文件先读入内存
+		content, ok := f.(io.ReadSeeker)
+		if !ok {
+			data, err := io.ReadAll(f)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			content = bytes.NewReader(data)
+		}
+		w.Header().Set("ETag", fileETag(info))
+		http.ServeContent(w, req, info.Name(), info.ModTime(), content)
+	})
+}
+
+// resolveStaticPath 函数用于将通配符捕获的相对路径解析为 rootDir 下的文件路径，
+// 如果解析结果跳出了 rootDir（例如包含 ../ 的路径穿越），则返回 false
+func resolveStaticPath(rootDir, name string) (string, bool) {
+	name, err := cleanRelativePath(name)
+	if err != nil {
+		return "", false
+	}
+
+	root, err := filepath.Abs(rootDir)
+	if err != nil {
+		return "", false
+	}
+	file := filepath.Join(root, filepath.FromSlash(name))
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return file, true
+}
+
+// cleanRelativePath 函数用于规范化以 / 分隔的相对路径，路径是绝对路径或通过 .. 跳出起始目录时返回错误
+func cleanRelativePath(name string) (string, error) {
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+		return "", fmt.Errorf("unsafe path %q", name)
+	}
+	return cleaned, nil
+}
+
+// FilePathParam 方法用于将通配符参数（例如 /static/*filepath 中的 filepath）作为相对文件路径读取，
+// 返回经过 path.Clean 规范化的路径；路径是绝对路径或试图通过 .. 跳出所在目录时返回错误。
+// 路由开启 CatchAllSlash 时，通配符参数开头的 / 会先被去掉
+func (c *Context) FilePathParam(key string) (string, error) {
+	name := c.Param(key)
+	if c.router != nil && c.router.CatchAllSlash {
+		name = strings.TrimPrefix(name, "/")
+	}
+	name, err := cleanRelativePath(name)
+	if err != nil {
+		return "", fmt.Errorf("path param %q: %w", key, err)
+	}
+	return name, nil
+}
+
+// fileETag 函数用于根据文件大小和修改时间生成 ETag
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
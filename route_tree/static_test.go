@@ -0,0 +1,223 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// writeFiles 函数用于在临时目录中创建测试文件，files 的键为相对路径，值为文件内容
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStatic(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"css/site.css": "body{}"})
+	r := newRouter()
+	r.Static("/assets", dir)
+
+	if w := serve(r, http.MethodGet, "/assets/css/site.css"); w.Code != http.StatusOK || w.Body.String() != "body{}" {
+		t.Fatalf("existing file: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if w := serve(r, http.MethodGet, "/assets/missing.css"); w.Code != http.StatusNotFound {
+		t.Fatalf("missing file: status = %d, want 404", w.Code)
+	}
+}
+
+func TestStaticRejectsTraversal(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"public/a.txt": "a", "secret.txt": "secret"})
+	r := newRouter()
+	r.Static("/assets", filepath.Join(dir, "public"))
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		// 未编码的 .. 路径段在查找路由之前就被拒绝
+		{"/assets/../secret.txt", http.StatusBadRequest},
+		// 编码后的 ../ 不会被请求路径的规范化处理，由 Static 自身拒绝
+		{"/assets/..%2fsecret.txt", http.StatusNotFound},
+		{"/assets/..%2f..%2fetc/passwd", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serve(r, http.MethodGet, tt.path); w.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, w.Code, tt.code)
+		}
+	}
+}
+
+func TestStaticConditionalGet(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"app.js": "console.log(1)"})
+	r := newRouter()
+	r.Static("/assets", dir)
+
+	w := serve(r, http.MethodGet, "/assets/app.js")
+	etag := w.Header().Get("ETag")
+	lastModified := w.Header().Get("Last-Modified")
+	if w.Code != http.StatusOK || etag == "" || lastModified == "" {
+		t.Fatalf("first GET: status = %d, ETag = %q, Last-Modified = %q", w.Code, etag, lastModified)
+	}
+
+	tests := []struct {
+		header, value string
+		code          int
+	}{
+		{"If-None-Match", etag, http.StatusNotModified},
+		{"If-None-Match", `"stale"`, http.StatusOK},
+		{"If-Modified-Since", lastModified, http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+		req.Header.Set(tt.header, tt.value)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: %s: status = %d, want %d", tt.header, tt.value, w.Code, tt.code)
+		}
+		if tt.code == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("%s: %s: body = %q, want empty", tt.header, tt.value, w.Body.String())
+		}
+	}
+}
+
+func TestStaticRange(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"video.bin": "0123456789"})
+	r := newRouter()
+	r.Static("/assets", dir)
+
+	tests := []struct {
+		rangeHeader  string
+		code         int
+		contentRange string
+		body         string
+	}{
+		{"bytes=2-5", http.StatusPartialContent, "bytes 2-5/10", "2345"},
+		{"bytes=7-", http.StatusPartialContent, "bytes 7-9/10", "789"},
+		{"bytes=20-30", http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/assets/video.bin", nil)
+		req.Header.Set("Range", tt.rangeHeader)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("Range %s: status = %d, want %d", tt.rangeHeader, w.Code, tt.code)
+			continue
+		}
+		if got := w.Header().Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("Range %s: Content-Range = %q, want %q", tt.rangeHeader, got, tt.contentRange)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("Range %s: body = %q, want %q", tt.rangeHeader, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("<h1>home</h1>")},
+		"css/app.css": {Data: []byte("body{}")},
+	}
+	r := newRouter()
+	r.StaticFS("/static", fsys)
+
+	tests := []struct {
+		path        string
+		code        int
+		body        string
+		contentType string
+	}{
+		{"/static/index.html", http.StatusOK, "<h1>home</h1>", "text/html; charset=utf-8"},
+		{"/static/css/app.css", http.StatusOK, "body{}", "text/css; charset=utf-8"},
+		{"/static/missing.js", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.path)
+		if w.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, w.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("GET %s: Content-Type = %q, want %q", tt.path, ct, tt.contentType)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"public/robots.txt": "User-agent: *\n"})
+	file := filepath.Join(dir, "public", "robots.txt")
+	r := newRouter()
+	r.File("/robots.txt", file)
+	r.File("/gone.txt", filepath.Join(dir, "missing.txt"))
+
+	w := serve(r, http.MethodGet, "/robots.txt")
+	if w.Code != http.StatusOK || w.Body.String() != "User-agent: *\n" {
+		t.Fatalf("GET /robots.txt: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	if w := serve(r, http.MethodGet, "/gone.txt"); w.Code != http.StatusNotFound {
+		t.Errorf("missing file: status = %d, want 404", w.Code)
+	}
+	// 文件在请求时才检查，注册之后删除同样返回 404
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, http.MethodGet, "/robots.txt"); w.Code != http.StatusNotFound {
+		t.Errorf("deleted file: status = %d, want 404", w.Code)
+	}
+}
+
+func TestFilePathParam(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"css/site.css", "css/site.css", false},
+		{"css/./img/../site.css", "css/site.css", false},
+		{"a/../../etc/passwd", "", true},
+		{"..", "", true},
+		{"/etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		c := &Context{Params: map[string]string{"filepath": tt.value}}
+		got, err := c.FilePathParam("filepath")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("FilePathParam(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// 开启 CatchAllSlash 时通配符参数以 / 开头，先去掉再检查
+	r := newRouter()
+	r.CatchAllSlash = true
+	r.addRouteCtx(http.MethodGet, "/static/*filepath", func(c *Context) {
+		name, err := c.FilePathParam("filepath")
+		c.String(http.StatusOK, "%s %v", name, err)
+	})
+	if w := serve(r, http.MethodGet, "/static/js/app.js"); w.Body.String() != "js/app.js <nil>" {
+		t.Errorf("CatchAllSlash: body = %q", w.Body.String())
+	}
+}
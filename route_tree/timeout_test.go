@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	r := newRouter()
+	r.Use(Timeout(20 * time.Millisecond))
+	lateWrite := make(chan struct{})
+	r.GET("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		// 超时响应已经写出，之后的写入不能再到达客户端
+		w.Write([]byte("too late"))
+		close(lateWrite)
+	})
+	r.GET("/fast/:id", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Id", newContext(w, req).Param("id"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	})
+
+	w := serve(r, http.MethodGet, "/slow")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("slow handler: status = %d, want 503", w.Code)
+	}
+	select {
+	case <-lateWrite:
+	case <-time.After(time.Second):
+		t.Fatal("slow handler did not observe the context deadline")
+	}
+	if got := w.Body.String(); got != "Service Unavailable\n" {
+		t.Errorf("slow handler: body = %q", got)
+	}
+
+	w = serve(r, http.MethodGet, "/fast/7")
+	if w.Code != http.StatusCreated || w.Body.String() != "done" || w.Header().Get("X-Id") != "7" {
+		t.Errorf("fast handler: status = %d, body = %q, X-Id = %q", w.Code, w.Body.String(), w.Header().Get("X-Id"))
+	}
+}
+
+func TestTimeoutCancelsContextDone(t *testing.T) {
+	r := newRouter()
+	r.Use(Timeout(10 * time.Millisecond))
+	result := make(chan error, 1)
+	r.addRouteCtx(http.MethodGet, "/work", func(c *Context) {
+		<-c.Done()
+		result <- c.Req.Context().Err()
+	})
+
+	serve(r, http.MethodGet, "/work")
+	select {
+	case err := <-result:
+		if err != context.DeadlineExceeded {
+			t.Errorf("context error = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("c.Done() was not closed by the Timeout middleware")
+	}
+}